@@ -0,0 +1,169 @@
+package astcontext
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// NewParserFromAST creates a new Parser from files which are already parsed
+// with the given file set, i.e. by a linter or a code generator. A single
+// file works with all queries, multiple files behave like a parsed directory.
+// The files have to be parsed with parser.ParseComments for the queries which
+// need comments. A Parser created from an AST can't be edited.
+func NewParserFromAST(fset *token.FileSet, files ...*ast.File) (*Parser, error) {
+	if fset == nil || len(files) == 0 {
+		return nil, ErrNoSource
+	}
+
+	p := &Parser{fset: fset}
+	if len(files) == 1 {
+		p.file = files[0]
+		return p, nil
+	}
+
+	p.pkgs = map[string]*ast.Package{}
+	for _, file := range files {
+		name := file.Name.Name
+		pkg, ok := p.pkgs[name]
+		if !ok {
+			pkg = &ast.Package{Name: name, Files: map[string]*ast.File{}}
+			p.pkgs[name] = pkg
+		}
+		pkg.Files[fset.Position(file.Pos()).Filename] = file
+	}
+
+	return p, nil
+}
+
+// Pos returns the token.Pos of the given byte offset in the parsed file. It
+// requires the parser to contain a single file.
+func (p *Parser) Pos(offset int) (token.Pos, error) {
+	return p.pos(offset)
+}
+
+// Offset returns the filename and the byte offset of the given token.Pos. It
+// returns an error if the position doesn't belong to the parsed files.
+func (p *Parser) Offset(pos token.Pos) (filename string, offset int, err error) {
+	tf := p.FileSet().File(pos)
+	if tf == nil {
+		return "", 0, ErrOffsetOutOfRange
+	}
+
+	ok, err := p.contains(tf)
+	if err != nil {
+		return "", 0, err
+	}
+	if !ok {
+		return "", 0, ErrOffsetOutOfRange
+	}
+
+	return tf.Name(), tf.Offset(pos), nil
+}
+
+// contains reports whether the given file is one of the parsed files
+func (p *Parser) contains(tf *token.File) (bool, error) {
+	files, err := p.files()
+	if err != nil {
+		return false, err
+	}
+
+	for _, file := range files {
+		if p.fset.File(file.Pos()) == tf {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// EnclosingFuncAt returns the innermost function enclosing the given
+// token.Pos. Only the functions of the file containing pos are considered.
+func (p *Parser) EnclosingFuncAt(pos token.Pos) (*Func, error) {
+	filename, offset, err := p.Offset(pos)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.file != nil {
+		return p.FuncIndex().EnclosingFunc(offset)
+	}
+
+	return p.Funcs().inFile(filename).EnclosingFunc(offset)
+}
+
+// NextFuncAt returns the nearest next function declaration for the given
+// token.Pos, shifted by shift. Only the functions of the file containing pos
+// are considered.
+func (p *Parser) NextFuncAt(pos token.Pos, shift int) (*Func, error) {
+	filename, offset, err := p.Offset(pos)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.file != nil {
+		return p.FuncIndex().NextFuncShift(offset, shift)
+	}
+
+	return p.Funcs().inFile(filename).Declarations().NextFuncShift(offset, shift)
+}
+
+// PrevFuncAt returns the nearest previous function declaration for the
+// given token.Pos, shifted by shift. Only the functions of the file
+// containing pos are considered.
+func (p *Parser) PrevFuncAt(pos token.Pos, shift int) (*Func, error) {
+	filename, offset, err := p.Offset(pos)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.file != nil {
+		return p.FuncIndex().PrevFuncShift(offset, shift)
+	}
+
+	return p.Funcs().inFile(filename).Declarations().PrevFuncShift(offset, shift)
+}
+
+// ContextAt returns the semantic path of the given token.Pos. It requires the
+// parser to contain a single file.
+func (p *Parser) ContextAt(pos token.Pos) (*Context, error) {
+	_, offset, err := p.Offset(pos)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Context(offset)
+}
+
+// CommentAt returns the comment group at the given token.Pos. It requires
+// the parser to contain a single file.
+func (p *Parser) CommentAt(pos token.Pos) (*Comment, error) {
+	_, offset, err := p.Offset(pos)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Comment(offset)
+}
+
+// Node returns the *ast.FuncDecl or *ast.FuncLit of the function. It's nil
+// for functions loaded from the cache or moved by Edit, the nodes of the
+// other functions belong to the AST before the Edit.
+func (f *Func) Node() ast.Node {
+	return f.node
+}
+
+// Node returns the *ast.TypeSpec of the type. It's nil for types loaded from
+// the cache or moved by Edit, see Func.Node.
+func (t *Type) Node() *ast.TypeSpec {
+	return t.node
+}
+
+// inFile returns a copy of funcs with only the functions of the given file
+func (f Funcs) inFile(filename string) Funcs {
+	var funcs Funcs
+	for _, fn := range f {
+		if fn.FuncPos.Filename == filename {
+			funcs = append(funcs, fn)
+		}
+	}
+	return funcs
+}
@@ -0,0 +1,143 @@
+package astcontext
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+const astSrcA = `package foo
+
+// A does things
+func A() {
+	f := func() {
+		// inner
+	}
+	f()
+}
+
+func B() {}
+`
+
+const astSrcB = `package foo
+
+type T struct{}
+
+func C() {}
+`
+
+func TestNewParserFromAST(t *testing.T) {
+	fset := token.NewFileSet()
+	a, err := parser.ParseFile(fset, "a.go", astSrcA, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewParserFromAST(fset, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos := a.Pos() + token.Pos(strings.Index(astSrcA, "// inner"))
+
+	fn, err := p.EnclosingFuncAt(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fn.IsLiteral() || fn.Node() == nil {
+		t.Errorf("want the literal with its node, got: %v", fn)
+	}
+
+	fn, err = p.NextFuncAt(pos, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fn.Signature.Name != "B" || fn.Node().(*ast.FuncDecl) != a.Decls[1] {
+		t.Errorf("want B, got: %v", fn)
+	}
+
+	fn, err = p.PrevFuncAt(pos, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fn.Signature.Name != "A" {
+		t.Errorf("want A, got: %v", fn)
+	}
+
+	if _, err := p.CommentAt(pos); err != nil {
+		t.Errorf("want comment, got: %v", err)
+	}
+
+	ctx, err := p.ContextAt(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Path != "pkg foo › func A › func literal #1" {
+		t.Errorf("wrong context: %q", ctx.Path)
+	}
+
+	offset, err := p.Pos(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filename, off, err := p.Offset(offset); err != nil || filename != "a.go" || off != 10 {
+		t.Errorf("Offset(Pos(10)) = %s, %d, %v", filename, off, err)
+	}
+}
+
+func TestNewParserFromAST_Files(t *testing.T) {
+	fset := token.NewFileSet()
+	a, err := parser.ParseFile(fset, "a.go", astSrcA, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := parser.ParseFile(fset, "b.go", astSrcB, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewParserFromAST(fset, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(p.Funcs()); n != 4 {
+		t.Errorf("want 4 funcs, got %d", n)
+	}
+	if n := len(p.Types()); n != 1 {
+		t.Errorf("want 1 type, got %d", n)
+	}
+
+	// only the functions of b.go are considered
+	fn, err := p.EnclosingFuncAt(b.Pos() + token.Pos(strings.Index(astSrcB, "func C")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fn.Signature.Name != "C" {
+		t.Errorf("want C, got: %v", fn)
+	}
+
+	if _, err := p.PrevFuncAt(b.Pos(), 0); !errors.Is(err, ErrNoFunc) {
+		t.Errorf("want no function before C, got: %v", err)
+	}
+
+	if _, err := p.ContextAt(b.Pos()); !errors.Is(err, ErrFileRequired) {
+		t.Errorf("want file required error, got: %v", err)
+	}
+
+	// positions of other files of the file set are rejected
+	other, err := parser.ParseFile(fset, "other.go", "package other\n", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.EnclosingFuncAt(other.Pos()); !errors.Is(err, ErrOffsetOutOfRange) {
+		t.Errorf("want out of range error, got: %v", err)
+	}
+
+	if _, err := NewParserFromAST(fset); !errors.Is(err, ErrNoSource) {
+		t.Errorf("want no source error, got: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package astcontext
+
+import (
+	"go/build"
+	"strings"
+)
+
+// BuildOptions defines the build context used to select the files of a
+// directory. Only files matching the build constraints (//go:build lines and
+// _GOOS/_GOARCH file name suffixes) are parsed.
+type BuildOptions struct {
+	// GOOS and GOARCH of the build context. If empty the values of the
+	// current environment are used.
+	GOOS   string
+	GOARCH string
+
+	// Tags defines additional build tags to satisfy, i.e: "integration"
+	Tags []string
+
+	// Tests includes the _test.go files of the package itself
+	Tests bool
+
+	// XTests includes the external test package (package foo_test)
+	XTests bool
+}
+
+// context returns the go/build context for the given options
+func (b *BuildOptions) context() build.Context {
+	ctx := build.Default
+	if b.GOOS != "" {
+		ctx.GOOS = b.GOOS
+	}
+	if b.GOARCH != "" {
+		ctx.GOARCH = b.GOARCH
+	}
+	ctx.BuildTags = append(ctx.BuildTags, b.Tags...)
+	return ctx
+}
+
+// match reports whether the file of the given directory matches the build
+// context. Test files are only matched if requested.
+func (b *BuildOptions) match(ctx *build.Context, dir, name string) bool {
+	isTest := strings.HasSuffix(name, "_test.go")
+	if isTest && !b.Tests && !b.XTests {
+		return false
+	}
+
+	match, err := ctx.MatchFile(dir, name)
+	return err == nil && match
+}
+
+// keep reports whether a matched file of the given package is kept. Test
+// files and external test packages are only kept if requested. A nil
+// BuildOptions keeps all files.
+func (b *BuildOptions) keep(pkg, filename string) bool {
+	if b == nil {
+		return true
+	}
+
+	if strings.HasSuffix(pkg, "_test") {
+		return b.XTests
+	}
+
+	return b.Tests || !strings.HasSuffix(filename, "_test.go")
+}
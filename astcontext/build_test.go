@@ -0,0 +1,78 @@
+package astcontext
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParser_Build(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"foo.go":          "package foo\n\nfunc Foo() {}\n",
+		"foo_linux.go":    "package foo\n\nfunc platform() {}\n",
+		"foo_windows.go":  "package foo\n\nfunc platform() {}\n",
+		"tagged.go":       "//go:build integration\n\npackage foo\n\nfunc Tagged() {}\n",
+		"foo_test.go":     "package foo\n\nfunc TestFoo() {}\n",
+		"foo_ext_test.go": "package foo_test\n\nfunc TestExt() {}\n",
+	}
+
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name  string
+		build *BuildOptions
+		want  []string
+	}{
+		{
+			name:  "all files",
+			build: nil,
+			want:  []string{"Foo", "Tagged", "TestExt", "TestFoo", "platform", "platform"},
+		},
+		{
+			name:  "linux",
+			build: &BuildOptions{GOOS: "linux"},
+			want:  []string{"Foo", "platform"},
+		},
+		{
+			name:  "tags",
+			build: &BuildOptions{GOOS: "windows", Tags: []string{"integration"}},
+			want:  []string{"Foo", "Tagged", "platform"},
+		},
+		{
+			name:  "tests",
+			build: &BuildOptions{GOOS: "linux", Tests: true},
+			want:  []string{"Foo", "TestFoo", "platform"},
+		},
+		{
+			name:  "xtests",
+			build: &BuildOptions{GOOS: "linux", XTests: true},
+			want:  []string{"Foo", "TestExt", "platform"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			parser, err := NewParser(&ParserOptions{Dir: dir, Build: tc.build})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, fn := range parser.Funcs() {
+				names = append(names, fn.Signature.Name)
+			}
+			sort.Strings(names)
+
+			if !reflect.DeepEqual(names, tc.want) {
+				t.Errorf("wrong functions:\nwant: %v\ngot:  %v", tc.want, names)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package astcontext
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// cacheVersion is the version of the cache files. It must be increased
+// whenever the stored data changes, so older files are ignored.
+const cacheVersion = 1
+
+// cacheEntry contains the declarations extracted from a single file. Entries
+// are stored in the cache file of their directory and are valid as long as
+// the size and modification time of the file are unchanged. If they changed,
+// the entry is still valid if the content hash is unchanged.
+type cacheEntry struct {
+	Path     string // absolute path of the file
+	Size     int64
+	ModTime  int64 // in nanoseconds
+	Hash     string
+	Comments bool // whether the comments were parsed
+	Package  string
+	Funcs    []cachedFunc
+	Types    Types
+
+	// name is the filename as passed to the parser, used for the positions
+	name string
+}
+
+// cachedFunc is a Func with the unexported fields that need to survive the
+// cache. The metrics are stored as well, as there is no AST to compute them
+// from.
+type cachedFunc struct {
+	*Func
+	Literal bool
+	Metrics *Metrics
+}
+
+// funcs returns the functions of the entry
+func (e *cacheEntry) funcs() Funcs {
+	funcs := make(Funcs, len(e.Funcs))
+	for i, f := range e.Funcs {
+		f.Func.literal = f.Literal
+		f.Func.metrics = f.Metrics
+		funcs[i] = f.Func
+	}
+	return funcs
+}
+
+// setName sets the filename of all positions of the entry
+func (e *cacheEntry) setName(name string) {
+	e.name = name
+	for _, f := range e.Funcs {
+		for _, pos := range []*Position{f.FuncPos, f.Lbrace, f.Rbrace, f.Doc} {
+			if pos != nil {
+				pos.Filename = name
+			}
+		}
+	}
+
+	for _, t := range e.Types {
+		for _, pos := range []*Position{t.TypePos, t.Doc} {
+			if pos != nil {
+				pos.Filename = name
+			}
+		}
+	}
+}
+
+// cachePath returns the path of the cache file of the given absolute
+// directory
+func cachePath(cacheDir, dir string) string {
+	sum := sha256.Sum256([]byte(dir))
+	return filepath.Join(cacheDir, hex.EncodeToString(sum[:])+".gob")
+}
+
+// cacheFile contains the entries of the files of a directory, by their
+// absolute paths. A directory is stored as a single file, so a single decode
+// restores all of its files.
+type cacheFile struct {
+	Version int
+	Entries map[string]*cacheEntry
+}
+
+// loadFile returns the cache entry of the given file. The entry of the old
+// cache file is used if it's still valid, otherwise the file is parsed. The
+// boolean is true if the entry changed and the cache file has to be written.
+func loadFile(old *cacheFile, name string, mode parser.Mode) (*cacheEntry, bool, error) {
+	path, err := filepath.Abs(name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := &cacheEntry{
+		Path:     path,
+		Size:     fi.Size(),
+		ModTime:  fi.ModTime().UnixNano(),
+		Comments: mode&parser.ParseComments != 0,
+	}
+
+	// the file is only read if it looks modified
+	e := old.Entries[path]
+	if e != nil && e.Comments == key.Comments && e.Size == key.Size && e.ModTime == key.ModTime {
+		e.setName(name)
+		return e, false, nil
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	sum := sha256.Sum256(src)
+	key.Hash = hex.EncodeToString(sum[:])
+
+	// touched, but not modified
+	if e != nil && e.Comments == key.Comments && e.Hash == key.Hash {
+		e.ModTime = key.ModTime
+		e.setName(name)
+		return e, true, nil
+	}
+
+	// every file gets its own file set, so entries don't depend on the
+	// offsets of other files
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, src, mode)
+	if err != nil {
+		return nil, false, err
+	}
+
+	e = key
+	e.Package = file.Name.Name
+	for _, fn := range collectFuncs(fset, []*ast.File{file}) {
+		e.Funcs = append(e.Funcs, cachedFunc{
+			Func:    fn,
+			Literal: fn.literal,
+			Metrics: NewMetrics(fset, fn.node),
+		})
+	}
+	e.Types = collectTypes(fset, []*ast.File{file})
+	e.name = name
+	return e, true, nil
+}
+
+// readCache reads the cache file at the given path. It returns an empty
+// cache if the file doesn't exist or can't be decoded.
+func readCache(path string) *cacheFile {
+	c := &cacheFile{}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return c
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(c); err != nil || c.Version != cacheVersion {
+		return &cacheFile{}
+	}
+
+	return c
+}
+
+// writeCache writes the cache file to the given path. The file is written to
+// a temporary file first and renamed, so concurrent readers never see a
+// partial file. Failures are ignored, the cache is only an optimization.
+func writeCache(path string, c *cacheFile) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(c); err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return
+	}
+
+	_, err = tmp.Write(buf.Bytes())
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil || os.Rename(tmp.Name(), path) != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// loadDir returns the cache entries of the Go files in the given directory,
+// sorted by package name and filename. The files are loaded concurrently,
+// the cache file is updated if any of their entries changed.
+func loadDir(cacheDir, dir string, opts *BuildOptions, mode parser.Mode) ([]*cacheEntry, error) {
+	paths, err := dirFiles(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cachePath := cachePath(cacheDir, absDir)
+	old := readCache(cachePath)
+
+	entries := make([]*cacheEntry, len(paths))
+	changed := make([]bool, len(paths))
+	errs := make([]error, len(paths))
+	forEach(len(paths), func(i int) {
+		entries[i], changed[i], errs[i] = loadFile(old, paths[i], mode)
+	})
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// files which were removed or don't match the build context anymore are
+	// dropped from the cache
+	stale := len(old.Entries) != len(entries)
+	c := &cacheFile{Version: cacheVersion, Entries: map[string]*cacheEntry{}}
+	for i, e := range entries {
+		stale = stale || changed[i]
+		c.Entries[e.Path] = e
+	}
+
+	if stale {
+		writeCache(cachePath, c)
+	}
+
+	var kept []*cacheEntry
+	for i, e := range entries {
+		if opts.keep(e.Package, paths[i]) {
+			kept = append(kept, e)
+		}
+	}
+
+	// paths are sorted already, the stable sort keeps them sorted per package
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].Package < kept[j].Package
+	})
+
+	return kept, nil
+}
@@ -0,0 +1,246 @@
+package astcontext
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writePackage writes a package of n generated files to the given directory
+func writePackage(tb testing.TB, dir string, n int) {
+	for i := 0; i < n; i++ {
+		src := fmt.Sprintf("package gen\n\n// T%d is generated\ntype T%d struct{ x int }\n\n%s", i, i,
+			generateSource(20)[len("package gen\n\n"):])
+		src = renameFuncs(src, i)
+		name := filepath.Join(dir, fmt.Sprintf("file%03d.go", i))
+		if err := os.WriteFile(name, []byte(src), 0o644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+// renameFuncs makes the generated functions of a file unique in the package
+func renameFuncs(src string, i int) string {
+	var out []byte
+	for j := 0; j < len(src); j++ {
+		out = append(out, src[j])
+		if src[j] == 'F' && j+1 < len(src) && src[j+1] >= '0' && src[j+1] <= '9' {
+			out = append(out, fmt.Sprintf("%d_", i)...)
+		}
+	}
+	return string(out)
+}
+
+// declsJSON returns the JSON encoding of the funcs, types and metrics of the
+// parser
+func declsJSON(t *testing.T, p *Parser) string {
+	var literals []bool
+	for _, fn := range p.Funcs() {
+		literals = append(literals, fn.IsLiteral())
+	}
+
+	b, err := json.Marshal(map[string]interface{}{
+		"funcs":    p.Funcs(),
+		"types":    p.Types(),
+		"literals": literals,
+		"ranked":   p.RankedFuncs(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestParser_Cache(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := t.TempDir()
+	writePackage(t, dir, 5)
+
+	parser, err := NewParser(&ParserOptions{Dir: dir, Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := declsJSON(t, parser)
+
+	// the first run fills the cache, the second one reads from it
+	for i := 0; i < 2; i++ {
+		parser, err := NewParser(&ParserOptions{Dir: dir, Comments: true, CacheDir: cacheDir})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := declsJSON(t, parser); got != want {
+			t.Fatalf("run %d: wrong declarations\nwant: %s\ngot:  %s", i, want, got)
+		}
+	}
+
+	absDir, _ := filepath.Abs(dir)
+	path := cachePath(cacheDir, absDir)
+	c := readCache(path)
+	if len(c.Entries) != 5 {
+		t.Fatalf("want 5 cache entries, got %d", len(c.Entries))
+	}
+
+	// tamper with an entry to make sure it's used
+	name := filepath.Join(dir, "file000.go")
+	abs, _ := filepath.Abs(name)
+	e := c.Entries[abs]
+	if e == nil {
+		t.Fatalf("no cache entry for %s", name)
+	}
+	e.Types[0].Signature.Name = "Cached"
+	writeCache(path, c)
+
+	parser, err = NewParser(&ParserOptions{Dir: dir, Comments: true, CacheDir: cacheDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parser.Types()[0].Signature.Name; got != "Cached" {
+		t.Errorf("cache entry is not used, got type %q", got)
+	}
+
+	// a touched file keeps its entry, only the modification time is updated
+	mtime := time.Now().Add(time.Hour)
+	if err := os.Chtimes(name, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	parser, err = NewParser(&ParserOptions{Dir: dir, Comments: true, CacheDir: cacheDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parser.Types()[0].Signature.Name; got != "Cached" {
+		t.Errorf("touched file is parsed again, got type %q", got)
+	}
+	if got := readCache(path).Entries[abs].ModTime; got != mtime.UnixNano() {
+		t.Errorf("modification time of the touched file is not updated, got %d", got)
+	}
+
+	// a changed file invalidates its entry
+	if err := os.WriteFile(name, []byte("package gen\n\ntype Changed int\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	parser, err = NewParser(&ParserOptions{Dir: dir, Comments: true, CacheDir: cacheDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	typ := parser.Types()[0]
+	if typ.Signature.Name != "Changed" || typ.TypePos.Filename != name {
+		t.Errorf("changed file is not parsed again, got type %q in %s", typ.Signature.Name, typ.TypePos.Filename)
+	}
+
+	// the entries depend on the comments option
+	parser, err = NewParser(&ParserOptions{Dir: dir, CacheDir: cacheDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fn := range parser.Funcs() {
+		if fn.Doc != nil {
+			t.Fatalf("doc of %s without parsing comments", fn)
+		}
+	}
+}
+
+func TestParser_CacheBuild(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := t.TempDir()
+	files := map[string]string{
+		"foo.go":          "package foo\n\nfunc Foo() {}\n",
+		"foo_windows.go":  "package foo\n\nfunc platform() {}\n",
+		"foo_test.go":     "package foo\n\nfunc TestFoo() {}\n",
+		"foo_ext_test.go": "package foo_test\n\nfunc TestExt() {}\n",
+	}
+
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	parser, err := NewParser(&ParserOptions{
+		Dir:      dir,
+		CacheDir: cacheDir,
+		Build:    &BuildOptions{GOOS: "linux", Tests: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, fn := range parser.Funcs() {
+		names = append(names, fn.Signature.Name)
+	}
+
+	if fmt.Sprint(names) != "[Foo TestFoo]" {
+		t.Errorf("wrong functions: %v", names)
+	}
+}
+
+func TestParser_CacheParseError(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.go"), []byte("package bad\n\nfunc {\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewParser(&ParserOptions{Dir: dir, CacheDir: t.TempDir()})
+	if ErrorCode(err) != CodeParseError {
+		t.Errorf("want parse error, got: %v", err)
+	}
+}
+
+func TestParser_CacheReparseError(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := t.TempDir()
+	name := filepath.Join(dir, "foo.go")
+	if err := os.WriteFile(name, []byte("package foo\n\n//go:generate foo\nfunc Foo() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	parser, err := NewParser(&ParserOptions{Dir: dir, Comments: true, CacheDir: cacheDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the directory is parsed again lazily, the file is broken by then
+	if err := os.WriteFile(name, []byte("package foo\n\nfunc {\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = parser.Run(&Query{Mode: "directives"})
+	if ErrorCode(err) != CodeParseError {
+		t.Errorf("want parse error, got: %v", err)
+	}
+}
+
+func BenchmarkNewParser_Dir(b *testing.B) {
+	dir := b.TempDir()
+	writePackage(b, dir, 200)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parser, err := NewParser(&ParserOptions{Dir: dir, Comments: true})
+		if err != nil {
+			b.Fatal(err)
+		}
+		parser.Funcs()
+	}
+}
+
+func BenchmarkNewParser_DirCache(b *testing.B) {
+	dir := b.TempDir()
+	cacheDir := b.TempDir()
+	writePackage(b, dir, 200)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parser, err := NewParser(&ParserOptions{Dir: dir, Comments: true, CacheDir: cacheDir})
+		if err != nil {
+			b.Fatal(err)
+		}
+		parser.Funcs()
+	}
+}
@@ -0,0 +1,363 @@
+package astcontext
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os"
+	"sort"
+	"strings"
+)
+
+// commentSpan is a comment group of the parsed file. End is exclusive.
+type commentSpan struct {
+	start, end int
+	startLine  int
+}
+
+// Comment returns the comment group at the given offset. It requires the
+// parser to contain a single file.
+func (p *Parser) Comment(offset int) (*Comment, error) {
+	if p.file == nil {
+		return nil, fmt.Errorf("comment %w", ErrFileRequired)
+	}
+
+	for _, c := range p.commentSpans() {
+		if c.start <= offset && c.end+1 >= offset {
+			return p.newComment(c.start), nil
+		}
+	}
+
+	return nil, ErrNoComment
+}
+
+// commentSpans returns the comment groups of the parsed file
+func (p *Parser) commentSpans() []commentSpan {
+	if p.comments == nil {
+		file := p.syntax()
+		p.comments = collectComments(p.fset, file)
+	}
+	return p.comments
+}
+
+// collectComments returns the comment groups of the given file
+func collectComments(fset *token.FileSet, file *ast.File) []commentSpan {
+	comments := []commentSpan{}
+	for _, c := range file.Comments {
+		start := fset.Position(c.Pos())
+		comments = append(comments, commentSpan{
+			start:     start.Offset,
+			end:       fset.Position(c.End()).Offset,
+			startLine: start.Line,
+		})
+	}
+	return comments
+}
+
+// newComment returns the comment group starting at the given offset
+func (p *Parser) newComment(offset int) *Comment {
+	file := p.syntax()
+	tf := p.fset.File(file.Pos())
+
+	i := sort.Search(len(file.Comments), func(i int) bool {
+		return tf.Offset(file.Comments[i].Pos()) >= offset
+	})
+	group := file.Comments[i]
+	first, last := group.List[0], group.List[len(group.List)-1]
+
+	start := p.fset.Position(group.Pos())
+	end := p.fset.Position(group.End())
+	c := &Comment{
+		StartLine: start.Line,
+		StartCol:  start.Column,
+		EndLine:   end.Line,
+		EndCol:    end.Column,
+		Kind:      "line",
+		Role:      "comment",
+	}
+
+	if strings.HasPrefix(first.Text, "/*") {
+		c.Kind = "block"
+	}
+
+	// the inner range starts at the text of the first comment and ends with
+	// the text of the last one
+	innerStart, _ := commentText(first)
+	_, innerEnd := commentText(last)
+	c.Inner = p.newRange(first.Pos()+token.Pos(innerStart), last.Pos()+token.Pos(innerEnd))
+
+	outerStart, outerEnd := p.outerRange(tf, start.Offset, end.Offset)
+	c.Outer = p.newRange(tf.Pos(outerStart), tf.Pos(outerEnd))
+
+	if decl := p.documented(file, group); decl != nil {
+		c.Decl = decl
+		c.Role = "doc"
+	}
+
+	if isDirectiveGroup(file, group) {
+		c.Role = "directive"
+	}
+	c.Directives = p.groupDirectives(file, group)
+
+	return c
+}
+
+// commentText returns the offsets of the text of the comment relative to its
+// start, without the markers and the surrounding whitespace. Both are right
+// after the opening marker for empty comments.
+func commentText(c *ast.Comment) (start, end int) {
+	start, end = 2, len(c.Text)
+	if strings.HasPrefix(c.Text, "/*") {
+		end -= 2
+	}
+
+	for start < end && isSpace(c.Text[start]) {
+		start++
+	}
+	for end > start && isSpace(c.Text[end-1]) {
+		end--
+	}
+
+	if start == end {
+		// the empty text is right after the opening marker
+		return 2, 2
+	}
+	return start, end
+}
+
+// outerRange returns the offsets of the outer range of the comment group
+// between the given offsets. Without the source it's the comment itself.
+func (p *Parser) outerRange(tf *token.File, start, end int) (int, int) {
+	src := p.source(tf)
+	if src == nil {
+		return start, end
+	}
+
+	lineStart := start
+	for lineStart > 0 && isSpace(src[lineStart-1]) && src[lineStart-1] != '\n' {
+		lineStart--
+	}
+
+	// a trailing comment after code only takes the whitespace before it
+	if lineStart > 0 && src[lineStart-1] != '\n' {
+		return lineStart, end
+	}
+
+	next, ok := nextLine(src, end)
+	if !ok {
+		// code follows the comment on the same line
+		return lineStart, end
+	}
+
+	if blank, ok := nextLine(src, next); ok && blank > next && isBlank(src[next:blank]) {
+		next = blank
+	}
+
+	return lineStart, next
+}
+
+// nextLine returns the offset of the line after the given offset, if only
+// whitespace follows the offset on its line. The end of the source counts as
+// a line.
+func nextLine(src []byte, offset int) (int, bool) {
+	for i := offset; i < len(src); i++ {
+		switch {
+		case src[i] == '\n':
+			return i + 1, true
+		case !isSpace(src[i]):
+			return offset, false
+		}
+	}
+	return len(src), true
+}
+
+// isBlank reports whether the given line only contains whitespace
+func isBlank(line []byte) bool {
+	return len(bytes.TrimSpace(line)) == 0
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
+
+// source returns the source of the given file. The file is read from disk
+// for parsers created from an AST, nil is returned if it can't be read.
+func (p *Parser) source(tf *token.File) []byte {
+	if p.src != nil {
+		return p.src
+	}
+
+	src, err := os.ReadFile(tf.Name())
+	if err != nil || len(src) != tf.Size() {
+		return nil
+	}
+	return src
+}
+
+// newRange returns the range between the given positions
+func (p *Parser) newRange(start, end token.Pos) Range {
+	s, e := p.fset.Position(start), p.fset.Position(end)
+	return Range{
+		StartLine: s.Line,
+		StartCol:  s.Column,
+		EndLine:   e.Line,
+		EndCol:    e.Column,
+	}
+}
+
+// isDirectiveGroup reports whether the comment group only contains
+// directives and build constraints
+func isDirectiveGroup(file *ast.File, group *ast.CommentGroup) bool {
+	for _, c := range group.List {
+		if !isDirective(c.Text) && !isBuildConstraint(file, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// isDirective reports whether the comment is a directive, i.e: //go:generate,
+// //line or //export. It follows the rules of go/ast, directives are line
+// comments without a space after the slashes. A bare //nolint is a directive
+// too.
+func isDirective(text string) bool {
+	if !strings.HasPrefix(text, "//") {
+		return false
+	}
+	c := text[2:]
+
+	if strings.HasPrefix(c, "line ") || strings.HasPrefix(c, "extern ") || strings.HasPrefix(c, "export ") {
+		return true
+	}
+
+	if c == "nolint" || strings.HasPrefix(c, "nolint ") {
+		return true
+	}
+
+	// "//[a-z0-9]+:[a-z0-9]"
+	colon := strings.Index(c, ":")
+	if colon <= 0 || colon+1 >= len(c) {
+		return false
+	}
+	for i := 0; i <= colon+1; i++ {
+		if i == colon {
+			continue
+		}
+		b := c[i]
+		if !('a' <= b && b <= 'z' || '0' <= b && b <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// documented returns the declaration documented by the given comment group,
+// or nil if it's not a doc comment
+func (p *Parser) documented(file *ast.File, group *ast.CommentGroup) *Decl {
+	if file.Doc == group {
+		return p.newDecl("package", file.Name.Name, "package "+file.Name.Name, file.Name)
+	}
+
+	// gen is the enclosing declaration of the specs
+	var gen *ast.GenDecl
+	var decl *Decl
+	ast.Inspect(file, func(n ast.Node) bool {
+		if decl != nil {
+			return false
+		}
+
+		switch x := n.(type) {
+		case *ast.FuncDecl:
+			if x.Doc == group {
+				decl = p.newDecl("func", x.Name.Name, NewFuncSignature(x).Full, x.Type)
+			}
+		case *ast.GenDecl:
+			gen = x
+			if x.Doc != group {
+				return true
+			}
+
+			keyword := x.Tok.String()
+			if x.Lparen.IsValid() || len(x.Specs) != 1 {
+				decl = p.newDecl(keyword, "", keyword+" (...)", x)
+				return false
+			}
+
+			decl = p.specDecl(keyword, x.Specs[0])
+		case *ast.TypeSpec:
+			if x.Doc == group {
+				decl = p.specDecl("type", x)
+			}
+		case *ast.ValueSpec:
+			if x.Doc == group {
+				decl = p.specDecl(gen.Tok.String(), x)
+			}
+		case *ast.ImportSpec:
+			if x.Doc == group {
+				decl = p.specDecl("import", x)
+			}
+		case *ast.Field:
+			if x.Doc != group {
+				return true
+			}
+
+			var names []string
+			for _, name := range x.Names {
+				names = append(names, name.Name)
+			}
+
+			buf := new(bytes.Buffer)
+			types.WriteExpr(buf, x.Type)
+			full := buf.String()
+			if len(names) > 0 {
+				full = strings.Join(names, ", ") + " " + full
+			}
+
+			decl = p.newDecl("field", strings.Join(names, ", "), full, x)
+		}
+		return true
+	})
+
+	return decl
+}
+
+// specDecl returns the declaration of a single spec. The keyword is the one
+// of the enclosing declaration.
+func (p *Parser) specDecl(keyword string, spec ast.Spec) *Decl {
+	switch x := spec.(type) {
+	case *ast.TypeSpec:
+		return p.newDecl("type", x.Name.Name, NewTypeSignature(x).Full, x.Name)
+	case *ast.ImportSpec:
+		return p.newDecl("import", x.Path.Value, "import "+x.Path.Value, x.Path)
+	case *ast.ValueSpec:
+		var names []string
+		for _, name := range x.Names {
+			names = append(names, name.Name)
+		}
+
+		full := keyword + " " + strings.Join(names, ", ")
+		if x.Type != nil {
+			buf := new(bytes.Buffer)
+			types.WriteExpr(buf, x.Type)
+			full += " " + buf.String()
+		}
+
+		return p.newDecl(keyword, strings.Join(names, ", "), full, x.Names[0])
+	}
+	return nil
+}
+
+// newDecl returns a Decl at the position of the given node
+func (p *Parser) newDecl(keyword, ident, full string, node ast.Node) *Decl {
+	pos := p.fset.Position(node.Pos())
+	return &Decl{
+		Keyword:  keyword,
+		Ident:    ident,
+		Full:     full,
+		Filename: pos.Filename,
+		Line:     pos.Line,
+		Col:      pos.Column,
+	}
+}
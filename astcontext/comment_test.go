@@ -0,0 +1,173 @@
+package astcontext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComment_TextObjects(t *testing.T) {
+	var src = `// Package main is documented
+package main
+
+// Foo does things
+//
+// and more things.
+func Foo() {
+	x := 1 // trailing
+	_ = x
+}
+
+/*  block  */
+
+//go:generate stringer -type=Kind
+//go:noinline
+func Bar() {}
+
+type T struct {
+	// Name is a field
+	Name string
+}
+
+var (
+	// A is a var
+	A = 1
+)
+
+//
+func Empty() {}
+`
+	parser, err := NewParser(&ParserOptions{Src: []byte(src), Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comment := func(text string) *Comment {
+		t.Helper()
+		c, err := parser.Comment(strings.Index(src, text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	cases := []struct {
+		text  string
+		kind  string
+		role  string
+		inner Range
+		outer Range
+		decl  string
+	}{
+		{
+			text:  "// Package main",
+			kind:  "line",
+			role:  "doc",
+			inner: Range{1, 4, 1, 30},
+			outer: Range{1, 1, 2, 1},
+			decl:  "package main",
+		},
+		{
+			text:  "// Foo does",
+			kind:  "line",
+			role:  "doc",
+			inner: Range{4, 4, 6, 20},
+			outer: Range{4, 1, 7, 1},
+			decl:  "func Foo()",
+		},
+		{
+			text:  "// trailing",
+			kind:  "line",
+			role:  "comment",
+			inner: Range{8, 12, 8, 20},
+			outer: Range{8, 8, 8, 20},
+		},
+		{
+			text:  "/*  block",
+			kind:  "block",
+			role:  "comment",
+			inner: Range{12, 5, 12, 10},
+			outer: Range{12, 1, 14, 1},
+		},
+		{
+			text:  "//go:generate",
+			kind:  "line",
+			role:  "directive",
+			inner: Range{14, 3, 15, 14},
+			outer: Range{14, 1, 16, 1},
+			decl:  "func Bar()",
+		},
+		{
+			text:  "// Name is",
+			kind:  "line",
+			role:  "doc",
+			inner: Range{19, 5, 19, 20},
+			outer: Range{19, 1, 20, 1},
+			decl:  "Name string",
+		},
+		{
+			text:  "// A is",
+			kind:  "line",
+			role:  "doc",
+			inner: Range{24, 5, 24, 15},
+			outer: Range{24, 1, 25, 1},
+			decl:  "var A",
+		},
+		{
+			text:  "//\nfunc Empty",
+			kind:  "line",
+			role:  "doc",
+			inner: Range{28, 3, 28, 3},
+			outer: Range{28, 1, 29, 1},
+			decl:  "func Empty()",
+		},
+	}
+
+	for _, tc := range cases {
+		c := comment(tc.text)
+		if c.Kind != tc.kind || c.Role != tc.role {
+			t.Errorf("%q: want %s %s, got %s %s", tc.text, tc.kind, tc.role, c.Kind, c.Role)
+		}
+
+		if c.Inner != tc.inner {
+			t.Errorf("%q: wrong inner range\nwant: %v\ngot:  %v", tc.text, tc.inner, c.Inner)
+		}
+
+		if c.Outer != tc.outer {
+			t.Errorf("%q: wrong outer range\nwant: %v\ngot:  %v", tc.text, tc.outer, c.Outer)
+		}
+
+		switch {
+		case tc.decl == "" && c.Decl != nil:
+			t.Errorf("%q: want no declaration, got: %v", tc.text, c.Decl)
+		case tc.decl != "" && (c.Decl == nil || c.Decl.Full != tc.decl):
+			t.Errorf("%q: want declaration %q, got: %v", tc.text, tc.decl, c.Decl)
+		}
+	}
+
+	// the declaration points to the func keyword
+	if d := comment("// Foo does").Decl; d.Line != 7 || d.Col != 1 || d.Ident != "Foo" {
+		t.Errorf("wrong position of the declaration: %+v", d)
+	}
+}
+
+func TestIsDirective(t *testing.T) {
+	cases := map[string]bool{
+		"//go:generate foo": true,
+		"//line foo.go:10":  true,
+		"//export Foo":      true,
+		"//nolint:errcheck": true,
+		"//nolint":          true,
+		"//nolintx":         false,
+		"// go:generate":    false,
+		"//Go:generate":     false,
+		"//go:":             false,
+		"/*go:generate*/":   false,
+		"// TODO: fix":      false,
+	}
+
+	for text, want := range cases {
+		if got := isDirective(text); got != want {
+			t.Errorf("isDirective(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package astcontext
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// ContextSeparator is used to join the segments of a Context into a single
+// path string
+const ContextSeparator = " › "
+
+// Segment represents a single element of the semantic path at an offset,
+// such as a package, type, function or a block statement.
+type Segment struct {
+	// Kind is one of: package, type, var, const, func, literal, for, range,
+	// if, switch, typeswitch, select, case
+	Kind string `json:"kind" vim:"kind"`
+
+	// Name is the human readable representation of the segment, i.e:
+	// "func (h *Handler) ServeHTTP" or "case \"GET\""
+	Name string `json:"name" vim:"name"`
+
+	StartLine int `json:"startLine" vim:"startLine"`
+	StartCol  int `json:"startCol" vim:"startCol"`
+	EndLine   int `json:"endLine" vim:"endLine"`
+	EndCol    int `json:"endCol" vim:"endCol"`
+}
+
+// Context specifies the result of the "context" mode
+type Context struct {
+	// Segments is the semantic path from the package down to the innermost
+	// block containing the offset
+	Segments []Segment `json:"segments" vim:"segments"`
+
+	// Path is the preformatted representation of Segments, to be used in
+	// statuslines
+	Path string `json:"path" vim:"path"`
+}
+
+// Context returns the semantic path of the given offset, i.e:
+//
+//	pkg server › type Handler › func (h *Handler) ServeHTTP › for loop
+//
+// It requires the parser to contain a single file.
+func (p *Parser) Context(offset int) (*Context, error) {
+	pos, err := p.pos(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	file := p.syntax()
+	ctx := &Context{}
+	add := func(kind, name string, node ast.Node) {
+		start := p.fset.Position(node.Pos())
+		end := p.fset.Position(node.End())
+		ctx.Segments = append(ctx.Segments, Segment{
+			Kind:      kind,
+			Name:      name,
+			StartLine: start.Line,
+			StartCol:  start.Column,
+			EndLine:   end.Line,
+			EndCol:    end.Column,
+		})
+	}
+
+	add("package", "pkg "+file.Name.Name, file)
+
+	// literals are numbered in the order of appearance inside their top level
+	// declaration
+	var decl ast.Decl
+	literals := 0
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil || n == file {
+			return true
+		}
+
+		if !(n.Pos() <= pos && pos <= n.End()) {
+			if _, ok := n.(*ast.FuncLit); ok && decl != nil && n.Pos() < pos {
+				literals++
+			}
+			return n.Pos() < pos && decl != nil
+		}
+
+		switch x := n.(type) {
+		case *ast.GenDecl, *ast.FuncDecl:
+			decl = x.(ast.Decl)
+		}
+
+		switch x := n.(type) {
+		case *ast.TypeSpec:
+			add("type", "type "+x.Name.Name, x)
+		case *ast.ValueSpec:
+			kind := "var"
+			if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.CONST {
+				kind = "const"
+			}
+
+			names := make([]string, 0, len(x.Names))
+			for _, name := range x.Names {
+				names = append(names, name.Name)
+			}
+			add(kind, kind+" "+strings.Join(names, ", "), x)
+		case *ast.FuncDecl:
+			name := "func " + x.Name.Name
+			if x.Recv != nil && len(x.Recv.List) != 0 {
+				recv := x.Recv.List[0]
+				if typ := p.receiverType(recv.Type); typ != nil {
+					add("type", "type "+typ.Name.Name, typ)
+				}
+
+				sig := NewFuncSignature(x)
+				name = fmt.Sprintf("func (%s) %s", sig.Recv, x.Name.Name)
+			}
+			add("func", name, x)
+		case *ast.FuncLit:
+			literals++
+			add("literal", fmt.Sprintf("func literal #%d", literals), x)
+		case *ast.ForStmt:
+			add("for", "for loop", x)
+		case *ast.RangeStmt:
+			add("range", "range loop", x)
+		case *ast.IfStmt:
+			add("if", "if", x)
+		case *ast.SwitchStmt:
+			add("switch", "switch", x)
+		case *ast.TypeSwitchStmt:
+			add("typeswitch", "type switch", x)
+		case *ast.SelectStmt:
+			add("select", "select", x)
+		case *ast.CaseClause:
+			if x.List == nil {
+				add("case", "default", x)
+				break
+			}
+
+			exprs := make([]string, 0, len(x.List))
+			for _, e := range x.List {
+				exprs = append(exprs, types.ExprString(e))
+			}
+			add("case", "case "+strings.Join(exprs, ", "), x)
+		case *ast.CommClause:
+			if x.Comm == nil {
+				add("case", "default", x)
+				break
+			}
+
+			buf := new(bytes.Buffer)
+			printer.Fprint(buf, token.NewFileSet(), x.Comm)
+			add("case", "case "+buf.String(), x)
+		}
+
+		return true
+	})
+
+	names := make([]string, 0, len(ctx.Segments))
+	for _, s := range ctx.Segments {
+		names = append(names, s.Name)
+	}
+	ctx.Path = strings.Join(names, ContextSeparator)
+
+	return ctx, nil
+}
+
+// receiverType returns the type declaration of the given receiver type
+// expression, if it's declared in the parsed file.
+func (p *Parser) receiverType(expr ast.Expr) *ast.TypeSpec {
+	// strip pointers and type parameters, i.e: *Handler[T] -> Handler
+	for done := false; !done; {
+		switch x := expr.(type) {
+		case *ast.StarExpr:
+			expr = x.X
+		case *ast.IndexExpr:
+			expr = x.X
+		case *ast.IndexListExpr:
+			expr = x.X
+		case *ast.ParenExpr:
+			expr = x.X
+		default:
+			done = true
+		}
+	}
+
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+
+	for _, decl := range p.syntax().Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range gd.Specs {
+			if ts := spec.(*ast.TypeSpec); ts.Name.Name == ident.Name {
+				return ts
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package astcontext
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	var src = `package server
+
+type Handler struct{}
+
+func (h *Handler) ServeHTTP(w int, r string) {
+	_ = func() {}
+	go func() {
+		for i := 0; i < 3; i++ {
+			switch r {
+			case "GET":
+				println(i)
+			}
+		}
+	}()
+}
+
+var (
+	a = 1
+	b = func() int { return a }
+)
+`
+	opts := &ParserOptions{Src: []byte(src)}
+	parser, err := NewParser(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		offset int
+		want   string
+	}{
+		{0, "pkg server"},
+		{30, "pkg server › type Handler"},
+		{70, "pkg server › type Handler › func (h *Handler) ServeHTTP"},
+		{100, "pkg server › type Handler › func (h *Handler) ServeHTTP › func literal #1"},
+		{174, "pkg server › type Handler › func (h *Handler) ServeHTTP › func literal #2 › for loop › switch › case \"GET\""},
+		{210, "pkg server › var a"},
+		{234, "pkg server › var b › func literal #1"},
+	}
+
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%v", tc.offset), func(t *testing.T) {
+			out, err := parser.Run(&Query{Mode: "context", Offset: tc.offset})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if out.Context.Path != tc.want {
+				t.Errorf("wrong path:\nwant: %s\ngot:  %s", tc.want, out.Context.Path)
+			}
+
+			if len(out.Context.Segments) == 0 {
+				t.Fatal("no segments")
+			}
+		})
+	}
+}
+
+func TestContext_Dir(t *testing.T) {
+	opts := &ParserOptions{Dir: "../testdata"}
+	parser, err := NewParser(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = parser.Run(&Query{Mode: "context", Offset: 10})
+	if !errorContains(err, "requires a file") {
+		t.Errorf("wrong error, got: %v", err)
+	}
+}
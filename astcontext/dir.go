@@ -0,0 +1,194 @@
+package astcontext
+
+import (
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// dirFiles returns the paths of the Go files in the given directory, sorted
+// by name. If opts is not nil, only the files matching the build context are
+// returned.
+func dirFiles(dir string, opts *BuildOptions) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ctx build.Context
+	if opts != nil {
+		ctx = opts.context()
+	}
+
+	var paths []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") {
+			continue
+		}
+
+		if opts != nil && !opts.match(&ctx, dir, name) {
+			continue
+		}
+
+		paths = append(paths, filepath.Join(dir, name))
+	}
+
+	return paths, nil
+}
+
+// parseDir parses the Go files in the given directory concurrently, the same
+// as parser.ParseDir does sequentially. If opts is not nil, only the files
+// matching the build context are parsed.
+func parseDir(fset *token.FileSet, dir string, opts *BuildOptions, mode parser.Mode) (map[string]*ast.Package, error) {
+	paths, err := dirFiles(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := parseFiles(fset, paths, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs := map[string]*ast.Package{}
+	for i, file := range files {
+		name := file.Name.Name
+		if !opts.keep(name, paths[i]) {
+			continue
+		}
+
+		pkg, ok := pkgs[name]
+		if !ok {
+			pkg = &ast.Package{Name: name, Files: map[string]*ast.File{}}
+			pkgs[name] = pkg
+		}
+		pkg.Files[paths[i]] = file
+	}
+
+	return pkgs, nil
+}
+
+// parseFiles parses the given files concurrently. It returns the error of the
+// first file which can't be parsed.
+func parseFiles(fset *token.FileSet, paths []string, mode parser.Mode) ([]*ast.File, error) {
+	files := make([]*ast.File, len(paths))
+	errs := make([]error, len(paths))
+
+	forEach(len(paths), func(i int) {
+		files[i], errs[i] = parser.ParseFile(fset, paths[i], nil, mode)
+	})
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// forEach calls fn for each index up to n, using a worker pool bounded by
+// GOMAXPROCS. It returns once all calls are finished.
+func forEach(n int, fn func(i int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+
+	var wg sync.WaitGroup
+	indexes := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// files returns the parsed files. Files of a directory are sorted by their
+// package name and filename, so the results don't depend on map ordering.
+func (p *Parser) files() ([]*ast.File, error) {
+	if p.file != nil {
+		return []*ast.File{p.syntax()}, nil
+	}
+
+	pkgs, err := p.packages()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(pkgs))
+	for name := range pkgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var files []*ast.File
+	for _, name := range names {
+		filenames := make([]string, 0, len(pkgs[name].Files))
+		for filename := range pkgs[name].Files {
+			filenames = append(filenames, filename)
+		}
+		sort.Strings(filenames)
+
+		for _, filename := range filenames {
+			files = append(files, pkgs[name].Files[filename])
+		}
+	}
+
+	return files, nil
+}
+
+// packages returns the parsed packages of a directory. If the declarations
+// were loaded from the cache, the directory is parsed on the first call. It
+// returns an error if the files can't be parsed anymore.
+func (p *Parser) packages() (map[string]*ast.Package, error) {
+	p.pkgsOnce.Do(func() {
+		if p.pkgs != nil || p.cached == nil {
+			return
+		}
+
+		paths := make([]string, len(p.cached))
+		for i, e := range p.cached {
+			paths[i] = e.name
+		}
+
+		// the files were parsed successfully before, but might have changed
+		// since then
+		files, err := parseFiles(p.fset, paths, p.mode)
+		if err != nil {
+			p.pkgsErr = toParseError(err)
+			return
+		}
+
+		p.pkgs = map[string]*ast.Package{}
+		for i, file := range files {
+			name := file.Name.Name
+			pkg, ok := p.pkgs[name]
+			if !ok {
+				pkg = &ast.Package{Name: name, Files: map[string]*ast.File{}}
+				p.pkgs[name] = pkg
+			}
+			pkg.Files[paths[i]] = file
+		}
+	})
+
+	return p.pkgs, p.pkgsErr
+}
@@ -0,0 +1,67 @@
+package astcontext
+
+import (
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParseDir(t *testing.T) {
+	dir := t.TempDir()
+	writePackage(t, dir, 20)
+
+	want, err := parser.ParseDir(token.NewFileSet(), dir, nil, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := parseDir(token.NewFileSet(), dir, nil, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, pkg := range want {
+		var wantFiles, gotFiles []string
+		for filename := range pkg.Files {
+			wantFiles = append(wantFiles, filename)
+		}
+		if got[name] != nil {
+			for filename := range got[name].Files {
+				gotFiles = append(gotFiles, filename)
+			}
+		}
+		sort.Strings(wantFiles)
+		sort.Strings(gotFiles)
+
+		if !reflect.DeepEqual(gotFiles, wantFiles) {
+			t.Errorf("package %s: wrong files\nwant: %v\ngot:  %v", name, wantFiles, gotFiles)
+		}
+	}
+}
+
+func TestParser_DirOrder(t *testing.T) {
+	dir := t.TempDir()
+	writePackage(t, dir, 20)
+
+	var want string
+	for i := 0; i < 5; i++ {
+		parser, err := NewParser(&ParserOptions{Dir: dir, Comments: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		funcs := parser.Funcs()
+		if len(funcs) == 0 || funcs[0].FuncPos.Filename != parser.Types()[0].TypePos.Filename {
+			t.Fatalf("files are not sorted: %v", funcs)
+		}
+
+		got := declsJSON(t, parser)
+		if i == 0 {
+			want = got
+		} else if got != want {
+			t.Fatalf("run %d: the order of declarations changed", i)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package astcontext
+
+import (
+	"go/ast"
+	"go/build/constraint"
+	"strconv"
+	"strings"
+)
+
+// Directive specifies a single comment of the "directives" mode, i.e:
+//
+//	//go:generate stringer -type=Kind
+type Directive struct {
+	// Name is the name of the directive, i.e: "go:generate", "go:build",
+	// "line" or "nolint". Legacy build constraints are named "+build".
+	Name string `json:"name" vim:"name"`
+
+	// Args are the arguments of the directive. Double quoted arguments of
+	// go:generate are unquoted, the comma separated values of tool
+	// directives like //nolint:errcheck,gosec are separate arguments and the
+	// expression of a //go:build constraint is a single argument.
+	Args []string `json:"args,omitempty" vim:"args,omitempty"`
+
+	// Text is the text of the comment, including the slashes
+	Text string `json:"text" vim:"text"`
+
+	// Decl is the declaration the directive is attached to, if any
+	Decl *Decl `json:"decl,omitempty" vim:"decl,omitempty"`
+
+	Filename string `json:"filename" vim:"filename"`
+	Line     int    `json:"line" vim:"line"`
+	Col      int    `json:"col" vim:"col"`
+}
+
+// Directives returns the directives of the parsed files, sorted by their
+// position. The source has to be parsed with comments.
+func (p *Parser) Directives() ([]Directive, error) {
+	files, err := p.files()
+	if err != nil {
+		return nil, err
+	}
+
+	directives := []Directive{}
+	for _, file := range files {
+		for _, group := range file.Comments {
+			directives = append(directives, p.groupDirectives(file, group)...)
+		}
+	}
+	return directives, nil
+}
+
+// groupDirectives returns the directives of the given comment group. They are
+// attached to the declaration the group documents.
+func (p *Parser) groupDirectives(file *ast.File, group *ast.CommentGroup) []Directive {
+	var directives []Directive
+	var decl *Decl
+	documented := false
+	for _, c := range group.List {
+		if !isDirective(c.Text) && !isBuildConstraint(file, c) {
+			continue
+		}
+
+		// the declaration is only looked up for groups with directives
+		if !documented {
+			decl = p.documented(file, group)
+			documented = true
+		}
+
+		name, args := parseDirective(c.Text)
+		pos := p.fset.Position(c.Pos())
+		directives = append(directives, Directive{
+			Name:     name,
+			Args:     args,
+			Text:     c.Text,
+			Decl:     decl,
+			Filename: pos.Filename,
+			Line:     pos.Line,
+			Col:      pos.Column,
+		})
+	}
+	return directives
+}
+
+// isBuildConstraint reports whether the comment is a //go:build or a legacy
+// // +build constraint. Constraints are only valid before the package clause.
+func isBuildConstraint(file *ast.File, c *ast.Comment) bool {
+	if c.Pos() >= file.Package {
+		return false
+	}
+	return constraint.IsGoBuild(c.Text) || constraint.IsPlusBuild(c.Text)
+}
+
+// parseDirective returns the name and the arguments of the given directive
+func parseDirective(text string) (string, []string) {
+	if constraint.IsPlusBuild(text) {
+		fields := strings.Fields(strings.TrimPrefix(text, "//"))
+		return fields[0], fields[1:]
+	}
+
+	name, rest, _ := strings.Cut(text[2:], " ")
+	rest = strings.TrimSpace(rest)
+
+	switch {
+	case name == "go:build":
+		if rest == "" {
+			return name, nil
+		}
+		return name, []string{rest}
+	case name == "go:generate":
+		return name, splitArgs(rest)
+	}
+
+	// tool directives can be followed by an explanation, i.e:
+	// //nolint:errcheck // the error is always nil
+	if i := strings.Index(rest, "//"); i >= 0 {
+		rest = strings.TrimSpace(rest[:i])
+	}
+
+	var args []string
+	if rest != "" {
+		args = strings.Fields(rest)
+	}
+
+	if strings.HasPrefix(name, "go:") {
+		return name, args
+	}
+
+	// //nolint:errcheck,gosec or //lint:ignore SA1019 reason
+	tool, values, ok := strings.Cut(name, ":")
+	if !ok {
+		return name, args
+	}
+
+	var toolArgs []string
+	for _, v := range strings.Split(values, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			toolArgs = append(toolArgs, v)
+		}
+	}
+	return tool, append(toolArgs, args...)
+}
+
+// splitArgs splits the arguments of a go:generate directive. Like go
+// generate, arguments are separated by spaces and double quoted arguments are
+// unquoted.
+func splitArgs(s string) []string {
+	var args []string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return args
+		}
+
+		if s[0] == '"' {
+			if quoted, err := strconv.QuotedPrefix(s); err == nil {
+				arg, _ := strconv.Unquote(quoted)
+				args = append(args, arg)
+				s = s[len(quoted):]
+				continue
+			}
+		}
+
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			end = len(s)
+		}
+		args = append(args, s[:end])
+		s = s[end:]
+	}
+}
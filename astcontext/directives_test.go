@@ -0,0 +1,114 @@
+package astcontext
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const directivesSrc = `//go:build linux && !386
+// +build linux,!386
+
+// Package main is documented
+package main
+
+import _ "embed"
+
+//go:generate stringer -type=Kind
+//go:generate sh -c "echo \"hello world\"" > out.txt
+
+//go:embed a.txt b.txt
+var data string
+
+// Foo does things.
+//
+//go:noinline
+func Foo() {
+	_ = 1 //nolint:errcheck,gosec // never fails
+	//nolint
+	//lint:ignore SA1019 still supported
+}
+
+// +build ignored after the package clause
+`
+
+func TestParser_Directives(t *testing.T) {
+	parser, err := NewParser(&ParserOptions{Src: []byte(directivesSrc), Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := parser.Run(&Query{Mode: "directives"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		name string
+		args []string
+		line int
+		col  int
+		decl string
+	}{
+		{name: "go:build", args: []string{"linux && !386"}, line: 1, col: 1},
+		{name: "+build", args: []string{"linux,!386"}, line: 2, col: 1},
+		{name: "go:generate", args: []string{"stringer", "-type=Kind"}, line: 9, col: 1},
+		{name: "go:generate", args: []string{"sh", "-c", `echo "hello world"`, ">", "out.txt"}, line: 10, col: 1},
+		{name: "go:embed", args: []string{"a.txt", "b.txt"}, line: 12, col: 1, decl: "var data string"},
+		{name: "go:noinline", line: 17, col: 1, decl: "func Foo()"},
+		{name: "nolint", args: []string{"errcheck", "gosec"}, line: 19, col: 8},
+		{name: "nolint", line: 20, col: 2},
+		{name: "lint", args: []string{"ignore", "SA1019", "still", "supported"}, line: 21, col: 2},
+	}
+
+	if len(res.Directives) != len(want) {
+		t.Fatalf("want %d directives, got %d: %+v", len(want), len(res.Directives), res.Directives)
+	}
+
+	for i, w := range want {
+		d := res.Directives[i]
+		if d.Name != w.name || !reflect.DeepEqual(d.Args, w.args) || d.Line != w.line || d.Col != w.col {
+			t.Errorf("directive %d: want %s %q at %d:%d, got %s %q at %d:%d",
+				i, w.name, w.args, w.line, w.col, d.Name, d.Args, d.Line, d.Col)
+		}
+
+		switch {
+		case w.decl == "" && d.Decl != nil:
+			t.Errorf("directive %d: want no declaration, got: %v", i, d.Decl)
+		case w.decl != "" && (d.Decl == nil || d.Decl.Full != w.decl):
+			t.Errorf("directive %d: want declaration %q, got: %v", i, w.decl, d.Decl)
+		}
+	}
+}
+
+func TestComment_Directives(t *testing.T) {
+	parser, err := NewParser(&ParserOptions{Src: []byte(directivesSrc), Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		text       string
+		role       string
+		directives int
+	}{
+		{text: "//go:build", role: "directive", directives: 2},
+		{text: "// Package main", role: "doc"},
+		{text: "//go:generate", role: "directive", directives: 2},
+		{text: "// Foo does", role: "doc", directives: 1},
+		{text: "//nolint:errcheck", role: "directive", directives: 1},
+		{text: "// +build ignored", role: "comment"},
+	}
+
+	for _, tc := range cases {
+		c, err := parser.Comment(strings.Index(directivesSrc, tc.text))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if c.Role != tc.role || len(c.Directives) != tc.directives {
+			t.Errorf("%q: want %s with %d directives, got %s with %d directives",
+				tc.text, tc.role, tc.directives, c.Role, len(c.Directives))
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package astcontext
+
+import (
+	"bytes"
+	"go/ast"
+	"go/token"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DocStub specifies an exported declaration without a doc comment, the
+// result of the "docstub" and "docstubs" modes
+type DocStub struct {
+	// Decl is the undocumented declaration
+	Decl Decl `json:"decl" vim:"decl"`
+
+	// Edit inserts the stub of the doc comment above the declaration, i.e:
+	// "// Foo ...". The offsets are the byte offsets of the file of the
+	// declaration.
+	Edit TextEdit `json:"edit" vim:"edit"`
+}
+
+// DocStubs returns the exported functions, methods and types of the parsed
+// files which don't have a doc comment, sorted by filename and position.
+// Methods are only returned if their receiver type is exported, types
+// declared inside of functions are ignored. Doc comments which only consist of
+// directives don't count, the stub is inserted above the directives. The
+// source has to be parsed with comments.
+func (p *Parser) DocStubs() []DocStub {
+	funcs := p.Funcs().Declarations()
+	sources := map[string][]byte{}
+	source := func(filename string) []byte {
+		src, ok := sources[filename]
+		if !ok {
+			src = p.readSource(filename)
+			sources[filename] = src
+		}
+		return src
+	}
+
+	stubs := []DocStub{}
+	for _, fn := range funcs {
+		if !token.IsExported(fn.Signature.Name) {
+			continue
+		}
+		if fn.Signature.Recv != "" && !token.IsExported(recvTypeName(fn.Signature.Recv)) {
+			continue
+		}
+
+		decl := Decl{
+			Keyword:  "func",
+			Ident:    fn.Signature.Name,
+			Full:     fn.Signature.Full,
+			Filename: fn.FuncPos.Filename,
+			Line:     fn.FuncPos.Line,
+			Col:      fn.FuncPos.Column,
+		}
+
+		if edit, ok := docStubEdit(source(decl.Filename), decl.Ident, fn.FuncPos, fn.Doc); ok {
+			stubs = append(stubs, DocStub{Decl: decl, Edit: edit})
+		}
+	}
+
+	for _, t := range p.Types() {
+		if !token.IsExported(t.Signature.Name) {
+			continue
+		}
+
+		// types inside of functions
+		if _, err := funcs.inFile(t.TypePos.Filename).EnclosingFunc(t.TypePos.Offset); err == nil {
+			continue
+		}
+
+		decl := Decl{
+			Keyword:  "type",
+			Ident:    t.Signature.Name,
+			Full:     t.Signature.Full,
+			Filename: t.TypePos.Filename,
+			Line:     t.TypePos.Line,
+			Col:      t.TypePos.Column,
+		}
+
+		if edit, ok := docStubEdit(source(decl.Filename), decl.Ident, t.TypePos, t.Doc); ok {
+			stubs = append(stubs, DocStub{Decl: decl, Edit: edit})
+		}
+	}
+
+	sort.SliceStable(stubs, func(i, j int) bool {
+		a, b := stubs[i], stubs[j]
+		if a.Decl.Filename != b.Decl.Filename {
+			return a.Decl.Filename < b.Decl.Filename
+		}
+		return a.Edit.Start < b.Edit.Start
+	})
+
+	return stubs
+}
+
+// DocStub returns the doc stub of the function or type declaration at the
+// given offset. It returns ErrNoDocStub if the declaration is documented or
+// not exported. It requires the parser to contain a single file.
+func (p *Parser) DocStub(offset int) (*DocStub, error) {
+	pos, err := p.pos(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	declPos := declAt(p.syntax(), pos)
+	if !declPos.IsValid() {
+		return nil, ErrNoDocStub
+	}
+
+	at := p.fset.Position(declPos)
+	for _, stub := range p.DocStubs() {
+		if stub.Decl.Line == at.Line && stub.Decl.Col == at.Column {
+			return &stub, nil
+		}
+	}
+
+	return nil, ErrNoDocStub
+}
+
+// declAt returns the position of the func keyword or the type name of the
+// top level declaration at the given position, including its doc comment.
+func declAt(file *ast.File, pos token.Pos) token.Pos {
+	within := func(doc *ast.CommentGroup, node ast.Node) bool {
+		start := node.Pos()
+		if doc != nil {
+			start = doc.Pos()
+		}
+		return start <= pos && pos <= node.End()
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if within(d.Doc, d) {
+				return d.Type.Func
+			}
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+
+			if !d.Lparen.IsValid() {
+				if within(d.Doc, d) && len(d.Specs) == 1 {
+					return d.Specs[0].(*ast.TypeSpec).Name.Pos()
+				}
+				continue
+			}
+
+			for _, spec := range d.Specs {
+				if s := spec.(*ast.TypeSpec); within(s.Doc, s) {
+					return s.Name.Pos()
+				}
+			}
+		}
+	}
+
+	return token.NoPos
+}
+
+// docStubEdit returns the edit inserting the doc stub of the declaration at
+// pos, documented by doc. It returns false if the declaration is documented.
+func docStubEdit(src []byte, ident string, pos, doc *Position) (TextEdit, bool) {
+	// the beginning of the line of the declaration
+	lineStart := pos.Offset - (pos.Column - 1)
+	indent := ""
+	if src != nil && pos.Offset <= len(src) {
+		line := src[lineStart:pos.Offset]
+		indent = string(line[:len(line)-len(bytes.TrimLeft(line, " \t"))])
+	}
+
+	stub := indent + "// " + ident + " ...\n"
+	if doc == nil {
+		return TextEdit{Start: lineStart, End: lineStart, Text: stub}, true
+	}
+
+	// a doc comment of directives, i.e: //go:noinline
+	docStart := doc.Offset - (doc.Column - 1)
+	if src == nil || docStart > lineStart || lineStart > len(src) {
+		return TextEdit{}, false
+	}
+
+	for _, line := range strings.Split(string(src[docStart:lineStart]), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !isDirective(line) {
+			return TextEdit{}, false
+		}
+	}
+
+	return TextEdit{Start: docStart, End: docStart, Text: stub + indent + "//\n"}, true
+}
+
+// recvTypeName returns the name of the type of the given receiver, i.e: "Foo"
+// for "f *Foo" or "s Set[K, V]"
+func recvTypeName(recv string) string {
+	name := recv
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, " "); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimLeft(name, "*")
+}
+
+// readSource returns the source of the given file. It's nil if the file
+// can't be read.
+func (p *Parser) readSource(filename string) []byte {
+	if p.src != nil {
+		return p.src
+	}
+
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+	return src
+}
@@ -0,0 +1,139 @@
+package astcontext
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const docStubsSrc = `package main
+
+// Foo is documented
+type Foo int
+
+func (f *Foo) Method() {}
+
+func (f Foo) unexported() {}
+
+type bar int
+
+func (b bar) Exported() {}
+
+//go:noinline
+func Directive() {}
+
+type (
+	// A is documented
+	A int
+	B[T any] struct{}
+)
+
+func (s *B[T]) Get() {}
+
+func Func() {
+	type Local int
+}
+`
+
+func TestParser_DocStubs(t *testing.T) {
+	parser, err := NewParser(&ParserOptions{Src: []byte(docStubsSrc), Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := parser.Run(&Query{Mode: "docstubs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		ident string
+		text  string
+	}{
+		{"Method", "// Method ...\n"},
+		{"Directive", "// Directive ...\n//\n"},
+		{"B", "\t// B ...\n"},
+		{"Get", "// Get ...\n"},
+		{"Func", "// Func ...\n"},
+	}
+
+	if len(res.DocStubs) != len(want) {
+		t.Fatalf("want %d stubs, got %d: %+v", len(want), len(res.DocStubs), res.DocStubs)
+	}
+
+	src := docStubsSrc
+	for i := len(res.DocStubs) - 1; i >= 0; i-- {
+		stub := res.DocStubs[i]
+		if stub.Decl.Ident != want[i].ident || stub.Edit.Text != want[i].text {
+			t.Errorf("stub %d: want %s %q, got %s %q", i, want[i].ident, want[i].text, stub.Decl.Ident, stub.Edit.Text)
+		}
+
+		src = src[:stub.Edit.Start] + stub.Edit.Text + src[stub.Edit.End:]
+	}
+
+	// applying the edits documents all declarations
+	parser, err = NewParser(&ParserOptions{Src: []byte(src), Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stubs := parser.DocStubs(); len(stubs) != 0 {
+		t.Errorf("want no stubs after the edits, got: %+v\n%s", stubs, src)
+	}
+
+	for _, text := range []string{"// Directive ...\n//\n//go:noinline\n", "\t// B ...\n\tB[T any]"} {
+		if !strings.Contains(src, text) {
+			t.Errorf("edited source doesn't contain %q:\n%s", text, src)
+		}
+	}
+}
+
+func TestParser_DocStub(t *testing.T) {
+	parser, err := NewParser(&ParserOptions{Src: []byte(docStubsSrc), Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stub, err := parser.DocStub(strings.Index(docStubsSrc, "B[T any]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stub.Decl.Keyword != "type" || stub.Decl.Ident != "B" || stub.Decl.Line != 20 || stub.Decl.Col != 2 {
+		t.Errorf("wrong stub: %+v", stub)
+	}
+
+	for _, text := range []string{"// Foo is", "func (b bar)", "type bar"} {
+		if _, err := parser.DocStub(strings.Index(docStubsSrc, text)); !errors.Is(err, ErrNoDocStub) {
+			t.Errorf("%q: want no doc stub error, got: %v", text, err)
+		}
+	}
+}
+
+func TestParser_DocStubsDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go": "package a\n\nfunc A() {}\n",
+		"b.go": "package a\n\n// B is documented\nfunc B() {}\n\ntype C int\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	parser, err := NewParser(&ParserOptions{Dir: dir, Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, stub := range parser.DocStubs() {
+		got = append(got, filepath.Base(stub.Decl.Filename)+" "+stub.Decl.Ident)
+	}
+
+	if strings.Join(got, ",") != "a.go A,b.go C" {
+		t.Errorf("wrong stubs: %q", got)
+	}
+}
@@ -0,0 +1,284 @@
+package astcontext
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"sync"
+)
+
+// TextEdit replaces the bytes between the offsets Start and End of the source
+// with Text
+type TextEdit struct {
+	Start int    `json:"start" vim:"start"`
+	End   int    `json:"end" vim:"end"`
+	Text  string `json:"text" vim:"text"`
+}
+
+// declSpan is the span of a top level declaration, including its doc comment.
+// End is exclusive.
+type declSpan struct {
+	start, end         int
+	startLine, endLine int
+}
+
+// snippetHeader is prepended to a declaration to parse it on its own
+const snippetHeader = "package p\n"
+
+// Edit applies the given edits to the source of a single file parser. Edits
+// are applied in order, the offsets of an edit refer to the source after all
+// previous edits, the same as the content changes of LSP.
+//
+// An edit inside of a single top level declaration only parses that
+// declaration again and updates Funcs, Types and the comments in place. The
+// AST is parsed again lazily, on the first query which needs it. Other edits,
+// or edits which change the boundaries of the declaration, parse the whole
+// source. If an edit results in invalid source, the error is returned and the
+// edit and all following edits are not applied.
+func (p *Parser) Edit(edits ...TextEdit) error {
+	if p.src == nil {
+		return fmt.Errorf("edit %w", ErrFileRequired)
+	}
+
+	for _, edit := range edits {
+		if edit.Start < 0 || edit.End < edit.Start || edit.End > len(p.src) {
+			return ErrOffsetOutOfRange
+		}
+
+		src := make([]byte, 0, len(p.src)-(edit.End-edit.Start)+len(edit.Text))
+		src = append(src, p.src[:edit.Start]...)
+		src = append(src, edit.Text...)
+		src = append(src, p.src[edit.End:]...)
+
+		if p.editDecl(edit, src) {
+			continue
+		}
+
+		if err := p.reparse(src); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reparse parses the given source of the file and resets all data computed
+// from the previous source
+func (p *Parser) reparse(src []byte) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, p.filename, src, p.mode)
+	if err != nil {
+		return toParseError(err)
+	}
+
+	p.fset, p.file, p.src, p.stale = fset, file, src, false
+	p.decls, p.comments = nil, nil
+	p.funcsOnce, p.funcs = sync.Once{}, nil
+	p.typesOnce, p.types = sync.Once{}, nil
+	p.indexOnce, p.index = sync.Once{}, nil
+	return nil
+}
+
+// syntax returns the AST of a single file, parsing it again if it's stale.
+// Edit made sure the source is valid, errors are ignored and the possibly
+// partial AST is returned.
+func (p *Parser) syntax() *ast.File {
+	if !p.stale {
+		return p.file
+	}
+
+	fset := token.NewFileSet()
+	file, _ := parser.ParseFile(fset, p.filename, p.src, p.mode)
+	if file != nil {
+		p.fset, p.file = fset, file
+	}
+	p.stale = false
+	return p.file
+}
+
+// declSpans returns the spans of the top level declarations
+func (p *Parser) declSpans() []declSpan {
+	if p.decls == nil {
+		file := p.syntax()
+		p.decls = []declSpan{}
+		for _, decl := range file.Decls {
+			p.decls = append(p.decls, declSpanOf(p.fset, decl))
+		}
+	}
+	return p.decls
+}
+
+// editDecl applies the edit if it's inside of a single top level declaration
+// by parsing only the declaration again. It returns false if the whole source
+// has to be parsed.
+func (p *Parser) editDecl(edit TextEdit, src []byte) bool {
+	// make sure everything which is updated in place exists
+	funcs, types := p.Funcs(), p.Types()
+	decls, comments := p.declSpans(), p.commentSpans()
+
+	i := sort.Search(len(decls), func(i int) bool { return decls[i].end >= edit.End })
+	if i == len(decls) || decls[i].start > edit.Start {
+		return false
+	}
+	d := decls[i]
+
+	// the declaration has to start a line and must not share its last line
+	// with the following declaration or comment, otherwise the columns of
+	// these would change
+	if d.start > 0 && p.src[d.start-1] != '\n' {
+		return false
+	}
+	if i+1 < len(decls) && decls[i+1].startLine == d.endLine {
+		return false
+	}
+	if c := sort.Search(len(comments), func(j int) bool { return comments[j].start >= d.end }); c < len(comments) &&
+		comments[c].startLine == d.endLine {
+		return false
+	}
+
+	delta := len(edit.Text) - (edit.End - edit.Start)
+	lineDelta := bytes.Count([]byte(edit.Text), []byte("\n")) -
+		bytes.Count(p.src[edit.Start:edit.End], []byte("\n"))
+
+	// parse the declaration on its own. It must still be a single
+	// declaration spanning the whole snippet.
+	snippet := append([]byte(snippetHeader), src[d.start:d.end+delta]...)
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, p.filename, snippet, p.mode)
+	if err != nil || len(file.Decls) != 1 {
+		return false
+	}
+
+	nd := declSpanOf(fset, file.Decls[0])
+	if nd.start != len(snippetHeader) || nd.end != len(snippet) {
+		return false
+	}
+
+	// positions of the snippet start at the second line
+	shift := func(pos *Position) *Position {
+		if pos == nil {
+			return nil
+		}
+		return &Position{
+			Filename: pos.Filename,
+			Offset:   pos.Offset - len(snippetHeader) + d.start,
+			Line:     pos.Line - 2 + d.startLine,
+			Column:   pos.Column,
+		}
+	}
+
+	// positions after the declaration move by the size of the edit
+	move := func(pos *Position) *Position {
+		if pos == nil {
+			return nil
+		}
+		return &Position{
+			Filename: pos.Filename,
+			Offset:   pos.Offset + delta,
+			Line:     pos.Line + lineDelta,
+			Column:   pos.Column,
+		}
+	}
+
+	// funcs and types are replaced instead of modified, previous results
+	// must stay valid. Their nodes don't belong to the AST anymore.
+	var newFuncs Funcs
+	for _, fn := range collectFuncs(fset, []*ast.File{file}) {
+		fn.FuncPos, fn.Lbrace, fn.Rbrace, fn.Doc = shift(fn.FuncPos), shift(fn.Lbrace), shift(fn.Rbrace), shift(fn.Doc)
+		fn.node = nil
+		newFuncs = append(newFuncs, fn)
+	}
+
+	from, to := funcRange(funcs, d)
+	for _, fn := range funcs[to:] {
+		moved := *fn
+		moved.FuncPos, moved.Lbrace, moved.Rbrace, moved.Doc = move(fn.FuncPos), move(fn.Lbrace), move(fn.Rbrace), move(fn.Doc)
+		moved.node = nil
+		newFuncs = append(newFuncs, &moved)
+	}
+	p.funcs = append(append(Funcs{}, funcs[:from]...), newFuncs...)
+
+	var newTypes Types
+	for _, typ := range collectTypes(fset, []*ast.File{file}) {
+		typ.TypePos, typ.Doc = shift(typ.TypePos), shift(typ.Doc)
+		typ.node = nil
+		newTypes = append(newTypes, typ)
+	}
+
+	from, to = typeRange(types, d)
+	for _, typ := range types[to:] {
+		moved := *typ
+		moved.TypePos, moved.Doc = move(typ.TypePos), move(typ.Doc)
+		moved.node = nil
+		newTypes = append(newTypes, &moved)
+	}
+	p.types = append(append(Types{}, types[:from]...), newTypes...)
+
+	// comments and declarations are owned by the parser and are updated in
+	// place
+	var newComments []commentSpan
+	for _, c := range collectComments(fset, file) {
+		c.start += d.start - len(snippetHeader)
+		c.end += d.start - len(snippetHeader)
+		c.startLine += d.startLine - 2
+		newComments = append(newComments, c)
+	}
+
+	from = sort.Search(len(comments), func(j int) bool { return comments[j].start >= d.start })
+	to = sort.Search(len(comments), func(j int) bool { return comments[j].start >= d.end })
+	for _, c := range comments[to:] {
+		c.start += delta
+		c.end += delta
+		c.startLine += lineDelta
+		newComments = append(newComments, c)
+	}
+	p.comments = append(comments[:from], newComments...)
+
+	decls[i].end += delta
+	decls[i].endLine += lineDelta
+	for j := i + 1; j < len(decls); j++ {
+		decls[j].start += delta
+		decls[j].end += delta
+		decls[j].startLine += lineDelta
+		decls[j].endLine += lineDelta
+	}
+
+	p.src, p.stale = src, true
+	p.indexOnce, p.index = sync.Once{}, nil
+	return true
+}
+
+// declSpanOf returns the span of the given top level declaration
+func declSpanOf(fset *token.FileSet, decl ast.Decl) declSpan {
+	start := decl.Pos()
+	switch x := decl.(type) {
+	case *ast.FuncDecl:
+		if x.Doc != nil {
+			start = x.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if x.Doc != nil {
+			start = x.Doc.Pos()
+		}
+	}
+
+	s, e := fset.Position(start), fset.Position(decl.End())
+	return declSpan{start: s.Offset, end: e.Offset, startLine: s.Line, endLine: e.Line}
+}
+
+// funcRange returns the range of the funcs inside of the given declaration
+func funcRange(funcs Funcs, d declSpan) (from, to int) {
+	from = sort.Search(len(funcs), func(i int) bool { return funcs[i].FuncPos.Offset >= d.start })
+	to = sort.Search(len(funcs), func(i int) bool { return funcs[i].FuncPos.Offset >= d.end })
+	return from, to
+}
+
+// typeRange returns the range of the types inside of the given declaration
+func typeRange(types Types, d declSpan) (from, to int) {
+	from = sort.Search(len(types), func(i int) bool { return types[i].TypePos.Offset >= d.start })
+	to = sort.Search(len(types), func(i int) bool { return types[i].TypePos.Offset >= d.end })
+	return from, to
+}
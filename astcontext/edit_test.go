@@ -0,0 +1,202 @@
+package astcontext
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// checkEdit compares the parser with a new parser of the given source
+func checkEdit(t *testing.T, p *Parser, src string) {
+	t.Helper()
+
+	want, err := NewParser(&ParserOptions{Src: []byte(src), Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := declsJSON(t, p), declsJSON(t, want); got != want {
+		t.Fatalf("wrong declarations after edit\nwant: %s\ngot:  %s", want, got)
+	}
+
+	if !reflect.DeepEqual(p.commentSpans(), want.commentSpans()) {
+		t.Fatalf("wrong comments after edit\nwant: %v\ngot:  %v", want.commentSpans(), p.commentSpans())
+	}
+
+	for _, offset := range []int{0, len(src) / 3, len(src) / 2, len(src) - 1} {
+		for _, mode := range []string{"enclosing", "context", "comment"} {
+			query := &Query{Mode: mode, Offset: offset}
+			wantRes, wantErr := want.Run(query)
+			gotRes, err := p.Run(query)
+			// funcs are compared by their JSON, the nodes differ
+			if !sameError(err, wantErr) || toJSON(t, gotRes) != toJSON(t, wantRes) {
+				t.Fatalf("%s at %d after edit\nwant: %+v, %v\ngot:  %+v, %v", mode, offset, wantRes, wantErr, gotRes, err)
+			}
+		}
+	}
+}
+
+func toJSON(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestParser_Edit(t *testing.T) {
+	src := "package main\n\n" +
+		"// A is a type\n" +
+		"type A struct {\n\tx int // x\n}\n\n" +
+		"// foo does things\n" +
+		"func foo() {\n\tbar := func() {}\n\tbar()\n}\n\n" +
+		"/* free comment */\n\n" +
+		"func baz() int { return 1 }\n"
+
+	p, err := NewParser(&ParserOptions{Src: []byte(src), Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := p.Funcs()
+
+	// edit returns whether the edit was incremental
+	edit := func(start, end int, text string) bool {
+		t.Helper()
+		if err := p.Edit(TextEdit{Start: start, End: end, Text: text}); err != nil {
+			t.Fatal(err)
+		}
+		incremental := p.stale
+		src = src[:start] + text + src[end:]
+		checkEdit(t, p, src)
+		return incremental
+	}
+
+	// inside of a function body, only the declaration is parsed
+	at := strings.Index(src, "bar()")
+	if !edit(at, at, "x := 1\n\t_ = x\n\t") {
+		t.Error("the declaration should be parsed incrementally")
+	}
+
+	// results returned before an edit are not modified
+	if old[len(old)-1].FuncPos.Line != 16 {
+		t.Errorf("previous result is modified: %v", old[len(old)-1])
+	}
+
+	// inside of a doc comment
+	at = strings.Index(src, "does things")
+	edit(at, at+len("does"), "really does")
+
+	// inside of a type
+	at = strings.Index(src, "x int")
+	edit(at, at+1, "field")
+
+	// a new declaration falls back to a full parse
+	at = strings.Index(src, "\tbar()\n}") + len("\tbar()\n}")
+	if edit(at, at, "\n\nfunc added() {}") {
+		t.Error("the source should be parsed again")
+	}
+
+	// between declarations
+	at = strings.Index(src, "/* free")
+	edit(at, at, "// another\n")
+
+	// removing a declaration
+	at = strings.Index(src, "func baz")
+	edit(at, len(src), "")
+}
+
+func TestParser_EditInvalid(t *testing.T) {
+	src := "package main\n\nfunc foo() {\n\tprintln()\n}\n"
+	p, err := NewParser(&ParserOptions{Src: []byte(src), Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := strings.Index(src, "println")
+	err = p.Edit(TextEdit{Start: at, End: at, Text: "func {"})
+	if ErrorCode(err) != CodeParseError {
+		t.Fatalf("want parse error, got: %v", err)
+	}
+
+	// the parser is unchanged
+	checkEdit(t, p, src)
+
+	if err := p.Edit(TextEdit{Start: 10, End: len(src) + 1}); !errors.Is(err, ErrOffsetOutOfRange) {
+		t.Errorf("want out of range error, got: %v", err)
+	}
+
+	dir, err := NewParser(&ParserOptions{Dir: "."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dir.Edit(TextEdit{}); !errors.Is(err, ErrFileRequired) {
+		t.Errorf("want file required error, got: %v", err)
+	}
+}
+
+func TestParser_EditRandom(t *testing.T) {
+	src := string(generateSource(30))
+	p, err := NewParser(&ParserOptions{Src: []byte(src), Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	texts := []string{"", "x", "\n", "// c\n", "_ = 1\n\t", "}", "func() {}()\n\t", "/*"}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		start := rnd.Intn(len(src))
+		end := start + rnd.Intn(5)
+		if end > len(src) {
+			end = len(src)
+		}
+		text := texts[rnd.Intn(len(texts))]
+
+		next := src[:start] + text + src[end:]
+		err := p.Edit(TextEdit{Start: start, End: end, Text: text})
+		if err == nil {
+			src = next
+		}
+
+		checkEdit(t, p, src)
+	}
+}
+
+func BenchmarkEdit(b *testing.B) {
+	src := generateSource(10000)
+	p, err := NewParser(&ParserOptions{Src: src, Comments: true})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// typing and deleting a character inside of the last function
+	at := strings.LastIndex(string(src), "return x") + len("return ")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		edit := TextEdit{Start: at, End: at, Text: "x"}
+		if i%2 == 1 {
+			edit = TextEdit{Start: at, End: at + 1}
+		}
+
+		if err := p.Edit(edit); err != nil {
+			b.Fatal(err)
+		}
+		p.Funcs().EnclosingFunc(at)
+	}
+}
+
+func BenchmarkEdit_Reparse(b *testing.B) {
+	src := generateSource(10000)
+	at := strings.LastIndex(string(src), "return x") + len("return ")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p, err := NewParser(&ParserOptions{Src: src, Comments: true})
+		if err != nil {
+			b.Fatal(err)
+		}
+		p.Funcs().EnclosingFunc(at)
+	}
+}
@@ -0,0 +1,207 @@
+package astcontext
+
+import (
+	"errors"
+	"fmt"
+	"go/scanner"
+	"io/fs"
+)
+
+var (
+	// ErrNoSource is returned if neither a file, src nor dir is specified
+	ErrNoSource = errors.New("file, src or dir is not specified")
+
+	// ErrReadSource is returned if the file or the directory can't be read.
+	// The error wraps the error of the os package as well, i.e: it matches
+	// fs.ErrNotExist or fs.ErrPermission.
+	ErrReadSource = errors.New("can't read the source")
+
+	// ErrFileRequired is returned by the modes which work on a single file if
+	// a directory was parsed
+	ErrFileRequired = errors.New("mode requires a file or src")
+
+	// ErrDirRequired is returned by the modes which walk a directory if a
+	// file was parsed
+	ErrDirRequired = errors.New("mode requires a dir")
+
+	// ErrOffsetOutOfRange is returned if the offset is outside of the file
+	ErrOffsetOutOfRange = errors.New("offset is outside of the file")
+
+	// ErrUnknownMode is returned by Run if the mode of the query is unknown
+	ErrUnknownMode = errors.New("unknown mode")
+
+	// ErrNoEnclosingFunc is returned if there is no function enclosing the
+	// offset
+	ErrNoEnclosingFunc = errors.New("no enclosing functions found")
+
+	// ErrNoFunc is returned if there is no next or previous function for the
+	// offset
+	ErrNoFunc = errors.New("no functions found")
+
+	// ErrShiftOutOfRange is returned if the shift is negative or there are
+	// not enough functions to shift by it
+	ErrShiftOutOfRange = errors.New("shift is out of range")
+
+	// ErrNoComment is returned if there is no comment at the offset
+	ErrNoComment = errors.New("no comment block at cursor position")
+
+	// ErrNoImports is returned if the file has no import declarations
+	ErrNoImports = errors.New("no imports found")
+
+	// ErrNoDocComment is returned if there is no documented declaration at
+	// the offset
+	ErrNoDocComment = errors.New("no doc comment at cursor position")
+
+	// ErrNoDocStub is returned if there is no undocumented exported
+	// declaration at the offset
+	ErrNoDocStub = errors.New("no undocumented exported declaration at cursor position")
+
+	// ErrNoExit is returned if there is no next or previous exit point of
+	// the enclosing function for the offset
+	ErrNoExit = errors.New("no exit points found")
+)
+
+// Error codes are stable identifiers of the errors, which can be used by
+// editors instead of the error messages.
+const (
+	CodeNoSource         = "no_source"
+	CodeReadError        = "read_error"
+	CodeFileRequired     = "file_required"
+	CodeDirRequired      = "dir_required"
+	CodeOffsetOutOfRange = "offset_out_of_range"
+	CodeUnknownMode      = "unknown_mode"
+	CodeNoEnclosingFunc  = "no_enclosing_func"
+	CodeNoFunc           = "no_func"
+	CodeShiftOutOfRange  = "shift_out_of_range"
+	CodeNoComment        = "no_comment"
+	CodeNoImports        = "no_imports"
+	CodeNoDocComment     = "no_doc_comment"
+	CodeNoDocStub        = "no_doc_stub"
+	CodeNoExit           = "no_exit"
+	CodeParseError       = "parse_error"
+	CodeUnknown          = "unknown"
+)
+
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrNoSource, CodeNoSource},
+	{ErrReadSource, CodeReadError},
+	{ErrFileRequired, CodeFileRequired},
+	{ErrDirRequired, CodeDirRequired},
+	{ErrOffsetOutOfRange, CodeOffsetOutOfRange},
+	{ErrUnknownMode, CodeUnknownMode},
+	{ErrNoEnclosingFunc, CodeNoEnclosingFunc},
+	{ErrNoFunc, CodeNoFunc},
+	{ErrShiftOutOfRange, CodeShiftOutOfRange},
+	{ErrNoComment, CodeNoComment},
+	{ErrNoImports, CodeNoImports},
+	{ErrNoDocComment, CodeNoDocComment},
+	{ErrNoDocStub, CodeNoDocStub},
+	{ErrNoExit, CodeNoExit},
+}
+
+// ErrorCode returns the error code of the given error, which is one of the
+// Code constants. It returns CodeUnknown for errors not returned by this
+// package.
+func ErrorCode(err error) string {
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		return CodeParseError
+	}
+
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+
+	return CodeUnknown
+}
+
+// ErrorResult is the result of a failed query
+type ErrorResult struct {
+	Err string `json:"err" vim:"err"`
+
+	// Code is one of the Code constants, which unlike the message is stable
+	Code string `json:"code" vim:"code"`
+
+	// Version is the SchemaVersion of the result
+	Version int `json:"version" vim:"version"`
+
+	// Errors are the syntax errors if Code is CodeParseError
+	Errors []SyntaxError `json:"errors,omitempty" vim:"errors,omitempty"`
+}
+
+// NewErrorResult returns the result of a query which failed with the given
+// error
+func NewErrorResult(err error) *ErrorResult {
+	res := &ErrorResult{
+		Err:     err.Error(),
+		Code:    ErrorCode(err),
+		Version: SchemaVersion,
+	}
+
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		res.Errors = parseErr.Errors
+	}
+
+	return res
+}
+
+// ParseError is returned by NewParser if the source contains syntax errors
+type ParseError struct {
+	// Errors are the syntax errors sorted by their position. The parser stops
+	// after the first ten errors.
+	Errors []SyntaxError `json:"errors" vim:"errors"`
+
+	err error
+}
+
+// SyntaxError is a single syntax error of a ParseError
+type SyntaxError struct {
+	Position
+	Msg string `json:"msg" vim:"msg"`
+}
+
+func (e *ParseError) Error() string { return e.err.Error() }
+
+// Unwrap returns the underlying scanner.ErrorList
+func (e *ParseError) Unwrap() error { return e.err }
+
+// toParseError converts the error of the go/parser package to a ParseError.
+// Failing to read the file or the directory is wrapped with ErrReadSource,
+// other errors are returned as they are.
+func toParseError(err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return fmt.Errorf("%w: %w", ErrReadSource, err)
+	}
+
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		return err
+	}
+
+	list.Sort()
+	e := &ParseError{err: err}
+	for _, l := range list {
+		e.Errors = append(e.Errors, SyntaxError{
+			Position: *ToPosition(l.Pos),
+			Msg:      l.Msg,
+		})
+	}
+
+	return e
+}
+
+// shiftError returns an error wrapping ErrShiftOutOfRange for the given
+// shift. What are the shifted items, i.e: "functions".
+func shiftError(shift int, what string) error {
+	if shift < 0 {
+		return fmt.Errorf("%w: shift can't be negative", ErrShiftOutOfRange)
+	}
+	return fmt.Errorf("%w: not enough %s to shift by %d", ErrShiftOutOfRange, what, shift)
+}
@@ -0,0 +1,123 @@
+package astcontext
+
+import (
+	"errors"
+	"go/scanner"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestErrorCode(t *testing.T) {
+	var src = `package main
+
+func foo() {}
+
+func bar() {}
+`
+	parser, err := NewParser(&ParserOptions{Src: []byte(src)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dirParser, err := NewParser(&ParserOptions{Dir: "."})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		parser *Parser
+		query  *Query
+		err    error
+		code   string
+	}{
+		{parser, &Query{Mode: "enclosing", Offset: 1}, ErrNoEnclosingFunc, CodeNoEnclosingFunc},
+		{parser, &Query{Mode: "next", Offset: 40}, ErrNoFunc, CodeNoFunc},
+		{parser, &Query{Mode: "prev", Offset: 1}, ErrNoFunc, CodeNoFunc},
+		{parser, &Query{Mode: "next", Offset: 1, Shift: 2}, ErrShiftOutOfRange, CodeShiftOutOfRange},
+		{parser, &Query{Mode: "prev", Offset: 40, Shift: 2}, ErrShiftOutOfRange, CodeShiftOutOfRange},
+		{parser, &Query{Mode: "next", Offset: 1, Shift: -1}, ErrShiftOutOfRange, CodeShiftOutOfRange},
+		{parser, &Query{Mode: "imports"}, ErrNoImports, CodeNoImports},
+		{parser, &Query{Mode: "reflowdoc", Offset: 15}, ErrNoDocComment, CodeNoDocComment},
+		{parser, &Query{Mode: "docstub", Offset: 15}, ErrNoDocStub, CodeNoDocStub},
+		{parser, &Query{Mode: "nextreturn", Offset: 15}, ErrNoExit, CodeNoExit},
+		{parser, &Query{Mode: "context", Offset: 9000}, ErrOffsetOutOfRange, CodeOffsetOutOfRange},
+		{parser, &Query{Mode: "foo"}, ErrUnknownMode, CodeUnknownMode},
+		{dirParser, &Query{Mode: "folds"}, ErrFileRequired, CodeFileRequired},
+		{parser, &Query{Mode: "symbols"}, ErrDirRequired, CodeDirRequired},
+		{parser, &Query{Mode: "todos", Recursive: true}, ErrDirRequired, CodeDirRequired},
+	}
+
+	for _, tc := range cases {
+		_, err := tc.parser.Run(tc.query)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%+v: wrong error\nwant: %v\ngot:  %v", tc.query, tc.err, err)
+		}
+
+		if code := ErrorCode(err); code != tc.code {
+			t.Errorf("%+v: wrong code\nwant: %s\ngot:  %s", tc.query, tc.code, code)
+		}
+	}
+
+	if code := ErrorCode(errors.New("foo")); code != CodeUnknown {
+		t.Errorf("wrong code of an unknown error: %s", code)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	var src = `package main
+
+func foo() {
+	x :=
+}
+
+func bar( {}
+`
+	_, err := NewParser(&ParserOptions{Src: []byte(src)})
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a *ParseError, got: %T %v", err, err)
+	}
+
+	if code := ErrorCode(err); code != CodeParseError {
+		t.Errorf("wrong code: %s", code)
+	}
+
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		t.Error("ParseError doesn't unwrap to scanner.ErrorList")
+	}
+
+	var lines []int
+	for _, e := range parseErr.Errors {
+		if e.Filename != "src.go" || e.Msg == "" {
+			t.Errorf("wrong syntax error: %+v", e)
+		}
+		lines = append(lines, e.Line)
+	}
+
+	if want := []int{5, 7}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("wrong lines\nwant: %v\ngot:  %v", want, lines)
+	}
+}
+
+func TestReadError(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	for _, opts := range []*ParserOptions{
+		{File: missing + ".go"},
+		{Dir: missing},
+		{Dir: missing, CacheDir: t.TempDir()},
+	} {
+		_, err := NewParser(opts)
+		if !errors.Is(err, ErrReadSource) || !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("expected ErrReadSource and os.ErrNotExist, got: %v", err)
+		}
+
+		if code := ErrorCode(err); code != CodeReadError {
+			t.Errorf("wrong code: %s", code)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package astcontext
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// exitCalls are the calls which exit a function, keyed by the import path and
+// the name of the function
+var exitCalls = map[string]bool{
+	"os.Exit":        true,
+	"log.Fatal":      true,
+	"log.Fatalf":     true,
+	"log.Fatalln":    true,
+	"log.Panic":      true,
+	"log.Panicf":     true,
+	"log.Panicln":    true,
+	"runtime.Goexit": true,
+}
+
+// Exit specifies an exit point of a function, the result of the "returns",
+// "nextreturn" and "prevreturn" modes
+type Exit struct {
+	// Kind is either "return", "panic" or the called function, i.e:
+	// "os.Exit" or "log.Fatalf"
+	Kind string `json:"kind" vim:"kind"`
+
+	// Text is the first line of the statement or the call
+	Text string `json:"text" vim:"text"`
+
+	Pos *Position `json:"pos" vim:"pos"` // start of the statement or call
+	End *Position `json:"end" vim:"end"` // end of the statement or call
+}
+
+// Exits returns the exit points of the innermost function enclosing the
+// given offset, sorted by their position: the return statements, the calls of
+// panic and the calls of os.Exit, log.Fatal, log.Panic (and their variants)
+// and runtime.Goexit. The exit points of nested function literals are
+// skipped. It requires the parser to contain a single file.
+func (p *Parser) Exits(offset int) ([]Exit, error) {
+	if p.file == nil {
+		return nil, fmt.Errorf("returns %w", ErrFileRequired)
+	}
+
+	fn, err := p.FuncIndex().EnclosingFunc(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	// the node of the current AST, the nodes of functions unchanged by Edit
+	// belong to the previous one
+	var body *ast.BlockStmt
+	switch x := p.funcNode(fn).(type) {
+	case *ast.FuncDecl:
+		body = x.Body
+	case *ast.FuncLit:
+		body = x.Body
+	}
+
+	exits := []Exit{}
+	if body == nil {
+		return exits, nil
+	}
+
+	file := p.syntax()
+	tf := p.fset.File(file.Pos())
+	src := p.source(tf)
+	imports := importPaths(file)
+
+	add := func(kind string, n ast.Node) {
+		start, end := p.fset.Position(n.Pos()), p.fset.Position(n.End())
+		text := kind
+		if src != nil {
+			line := src[start.Offset:end.Offset]
+			if i := bytes.IndexByte(line, '\n'); i >= 0 {
+				line = line[:i]
+			}
+			text = string(bytes.TrimSpace(line))
+		}
+
+		exits = append(exits, Exit{
+			Kind: kind,
+			Text: text,
+			Pos:  ToPosition(start),
+			End:  ToPosition(end),
+		})
+	}
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			add("return", x)
+		case *ast.CallExpr:
+			if kind := exitCall(x, imports); kind != "" {
+				add(kind, x)
+			}
+		}
+		return true
+	})
+
+	return exits, nil
+}
+
+// NextExit returns the nearest next exit point of the function enclosing the
+// given offset, shifted by shift. See Funcs.NextFuncShift.
+func (p *Parser) NextExit(offset, shift int) (*Exit, error) {
+	if shift < 0 {
+		return nil, shiftError(shift, "exit points")
+	}
+
+	exits, err := p.Exits(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	next := sort.Search(len(exits), func(i int) bool {
+		return exits[i].Pos.Offset > offset
+	})
+
+	switch {
+	case next >= len(exits):
+		return nil, ErrNoExit
+	case next+shift >= len(exits):
+		return nil, shiftError(shift, "exit points")
+	}
+
+	return &exits[next+shift], nil
+}
+
+// PrevExit returns the nearest previous exit point of the function enclosing
+// the given offset, shifted by shift. See Funcs.PrevFuncShift.
+func (p *Parser) PrevExit(offset, shift int) (*Exit, error) {
+	if shift < 0 {
+		return nil, shiftError(shift, "exit points")
+	}
+
+	exits, err := p.Exits(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	prev := sort.Search(len(exits), func(i int) bool {
+		return exits[i].Pos.Offset >= offset
+	}) - 1
+
+	switch {
+	case prev < 0:
+		return nil, ErrNoExit
+	case prev-shift < 0:
+		return nil, shiftError(shift, "exit points")
+	}
+
+	return &exits[prev-shift], nil
+}
+
+// exitCall returns the kind of the exit point if the given call exits the
+// function, i.e: "panic" or "os.Exit". Imports are the import paths of the
+// file keyed by their names.
+func exitCall(call *ast.CallExpr, imports map[string]string) string {
+	switch fn := call.Fun.(type) {
+	case *ast.Ident:
+		// the builtin, unless it's shadowed
+		if fn.Name == "panic" && fn.Obj == nil {
+			return "panic"
+		}
+	case *ast.SelectorExpr:
+		x, ok := fn.X.(*ast.Ident)
+		if !ok || x.Obj != nil {
+			return ""
+		}
+
+		kind := imports[x.Name] + "." + fn.Sel.Name
+		if exitCalls[kind] {
+			return kind
+		}
+	}
+	return ""
+}
+
+// importPaths returns the import paths of the given file keyed by the name
+// they are referred to. Blank and dot imports are skipped.
+func importPaths(file *ast.File) map[string]string {
+	paths := map[string]string{}
+	for _, spec := range file.Imports {
+		p, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+
+		name := path.Base(p)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+
+		if name != "_" && name != "." {
+			paths[name] = p
+		}
+	}
+	return paths
+}
@@ -0,0 +1,151 @@
+package astcontext
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const exitsSrc = `package main
+
+import (
+	"log"
+	"os"
+	stdruntime "runtime"
+)
+
+func Foo(n int) (int, error) {
+	if n < 0 {
+		panic("negative")
+	}
+
+	defer func() {
+		return
+	}()
+
+	switch n {
+	case 0:
+		os.Exit(1)
+	case 1:
+		log.Fatalf("one: %d",
+			n)
+	case 2:
+		stdruntime.Goexit()
+	}
+
+	panic := func(string) {}
+	panic("shadowed")
+
+	return n, nil
+}
+
+func Bar() {
+	log.Println("not an exit")
+}
+`
+
+func TestParser_Exits(t *testing.T) {
+	parser, err := NewParser(&ParserOptions{Src: []byte(exitsSrc)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offset := strings.Index(exitsSrc, "if n < 0")
+	res, err := parser.Run(&Query{Mode: "returns", Offset: offset})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		kind string
+		text string
+		line int
+	}{
+		{"panic", `panic("negative")`, 11},
+		{"os.Exit", "os.Exit(1)", 20},
+		{"log.Fatalf", `log.Fatalf("one: %d",`, 22},
+		{"runtime.Goexit", "stdruntime.Goexit()", 25},
+		{"return", "return n, nil", 31},
+	}
+
+	if len(res.Exits) != len(want) {
+		t.Fatalf("want %d exits, got %d: %+v", len(want), len(res.Exits), res.Exits)
+	}
+
+	for i, w := range want {
+		e := res.Exits[i]
+		if e.Kind != w.kind || e.Text != w.text || e.Pos.Line != w.line {
+			t.Errorf("exit %d: want %s %q at line %d, got %s %q at line %d",
+				i, w.kind, w.text, w.line, e.Kind, e.Text, e.Pos.Line)
+		}
+	}
+
+	if end := res.Exits[2].End; end.Line != 23 {
+		t.Errorf("wrong end of the multi line call: %+v", end)
+	}
+
+	// the literal has its own exit points
+	exits, err := parser.Exits(strings.Index(exitsSrc, "return\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exits) != 1 || exits[0].Kind != "return" {
+		t.Errorf("wrong exits of the literal: %+v", exits)
+	}
+
+	exits, err = parser.Exits(strings.Index(exitsSrc, "log.Println"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exits) != 0 {
+		t.Errorf("want no exits, got: %+v", exits)
+	}
+
+	if _, err := parser.Exits(1); !errors.Is(err, ErrNoEnclosingFunc) {
+		t.Errorf("want no enclosing func error, got: %v", err)
+	}
+}
+
+func TestParser_NextPrevExit(t *testing.T) {
+	parser, err := NewParser(&ParserOptions{Src: []byte(exitsSrc)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offset := strings.Index(exitsSrc, "switch n")
+	cases := []struct {
+		mode  string
+		shift int
+		kind  string
+		err   error
+	}{
+		{mode: "nextreturn", kind: "os.Exit"},
+		{mode: "nextreturn", shift: 3, kind: "return"},
+		{mode: "nextreturn", shift: 4, err: ErrShiftOutOfRange},
+		{mode: "nextreturn", shift: -1, err: ErrShiftOutOfRange},
+		{mode: "prevreturn", kind: "panic"},
+		{mode: "prevreturn", shift: 1, err: ErrShiftOutOfRange},
+	}
+
+	for _, tc := range cases {
+		res, err := parser.Run(&Query{Mode: tc.mode, Offset: offset, Shift: tc.shift})
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%s %d: want error %v, got: %v", tc.mode, tc.shift, tc.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Exits) != 1 || res.Exits[0].Kind != tc.kind {
+			t.Errorf("%s %d: want %s, got: %+v", tc.mode, tc.shift, tc.kind, res.Exits)
+		}
+	}
+
+	offset = strings.Index(exitsSrc, "return n, nil") + 1
+	if _, err := parser.NextExit(offset, 0); !errors.Is(err, ErrNoExit) {
+		t.Errorf("want no exit error, got: %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package astcontext
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+)
+
+// Fold represents a foldable region spanning multiple lines. The end position
+// is the position of the last character of the region, i.e: the closing
+// brace.
+type Fold struct {
+	// Kind is one of: func, literal, struct, interface, composite, imports,
+	// comment
+	Kind string `json:"kind" vim:"kind"`
+
+	StartLine int `json:"startLine" vim:"startLine"`
+	StartCol  int `json:"startCol" vim:"startCol"`
+	EndLine   int `json:"endLine" vim:"endLine"`
+	EndCol    int `json:"endCol" vim:"endCol"`
+}
+
+// Folds returns all regions of the parsed file which span multiple lines and
+// can be folded: function bodies, function literals, struct and interface
+// bodies, composite literals, import declarations and groups and comments.
+// Consecutive comment groups are folded together. The folds are sorted by
+// their start position.
+func (p *Parser) Folds() ([]Fold, error) {
+	if p.file == nil {
+		return nil, fmt.Errorf("folds %w", ErrFileRequired)
+	}
+
+	file := p.syntax()
+	folds := []Fold{}
+	add := func(kind string, start, end token.Position) {
+		if start.Line == end.Line {
+			return
+		}
+
+		folds = append(folds, Fold{
+			Kind:      kind,
+			StartLine: start.Line,
+			StartCol:  start.Column,
+			EndLine:   end.Line,
+			EndCol:    end.Column,
+		})
+	}
+
+	for _, fn := range p.Funcs() {
+		// forward declarations don't have a body
+		if fn.Lbrace == nil || fn.Rbrace == nil {
+			continue
+		}
+
+		kind := "func"
+		if fn.IsLiteral() {
+			kind = "literal"
+		}
+
+		add(kind, toTokenPosition(fn.Lbrace), toTokenPosition(fn.Rbrace))
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.StructType:
+			add("struct", p.fset.Position(x.Fields.Opening), p.fset.Position(x.Fields.Closing))
+		case *ast.InterfaceType:
+			add("interface", p.fset.Position(x.Methods.Opening), p.fset.Position(x.Methods.Closing))
+		case *ast.CompositeLit:
+			add("composite", p.fset.Position(x.Lbrace), p.fset.Position(x.Rbrace))
+		}
+		return true
+	})
+
+	if imports, err := p.Imports(); err == nil {
+		for _, decl := range file.Decls {
+			if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.IMPORT {
+				add("imports", p.fset.Position(gd.Pos()), p.fset.Position(gd.End()-1))
+			}
+		}
+
+		// fold the groups only if there is more than one of them, otherwise
+		// they are the same as the declaration
+		specs := imports.Specs
+		if len(specs) > 0 && specs[len(specs)-1].Group > 0 {
+			start := 0
+			for i := 1; i <= len(specs); i++ {
+				if i < len(specs) && specs[i].Group == specs[start].Group {
+					continue
+				}
+
+				first, last := specs[start], specs[i-1]
+				add("imports",
+					token.Position{Line: first.StartLine, Column: first.StartCol},
+					token.Position{Line: last.EndLine, Column: last.EndCol - 1})
+				start = i
+			}
+		}
+	}
+
+	var comments []*ast.CommentGroup
+	for _, c := range file.Comments {
+		if n := len(comments); n > 0 &&
+			p.fset.Position(c.Pos()).Line == p.fset.Position(comments[n-1].End()).Line+1 {
+			comments[n-1] = &ast.CommentGroup{
+				List: append(append([]*ast.Comment{}, comments[n-1].List...), c.List...),
+			}
+			continue
+		}
+		comments = append(comments, c)
+	}
+
+	for _, c := range comments {
+		add("comment", p.fset.Position(c.Pos()), p.fset.Position(c.End()-1))
+	}
+
+	sort.SliceStable(folds, func(i, j int) bool {
+		a, b := folds[i], folds[j]
+		if a.StartLine != b.StartLine {
+			return a.StartLine < b.StartLine
+		}
+		return a.StartCol < b.StartCol
+	})
+
+	return folds, nil
+}
+
+// toTokenPosition returns a token.Position from the given Position
+func toTokenPosition(pos *Position) token.Position {
+	return token.Position{
+		Filename: pos.Filename,
+		Offset:   pos.Offset,
+		Line:     pos.Line,
+		Column:   pos.Column,
+	}
+}
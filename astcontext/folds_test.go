@@ -0,0 +1,67 @@
+package astcontext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFolds(t *testing.T) {
+	var src = `package main
+
+import (
+	"fmt"
+	"os"
+
+	"strings"
+)
+
+// Doc line one
+// line two
+/* block */
+type T struct {
+	A int
+}
+
+type I interface {
+	M()
+}
+
+var x = []int{
+	1,
+}
+
+var y = []int{1}
+
+func main() {
+	f := func() {
+		fmt.Println(os.Args, strings.Join)
+	}
+	f()
+}
+`
+	opts := &ParserOptions{Src: []byte(src), Comments: true}
+	parser, err := NewParser(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := parser.Run(&Query{Mode: "folds"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Fold{
+		{"imports", 3, 1, 8, 1},
+		{"imports", 4, 2, 5, 5},
+		{"comment", 10, 1, 12, 11},
+		{"struct", 13, 15, 15, 1},
+		{"interface", 17, 18, 19, 1},
+		{"composite", 21, 14, 23, 1},
+		{"func", 27, 13, 32, 1},
+		{"literal", 28, 14, 30, 2},
+	}
+
+	if !reflect.DeepEqual(out.Folds, want) {
+		t.Errorf("wrong folds:\nwant: %v\ngot:  %v", want, out.Folds)
+	}
+}
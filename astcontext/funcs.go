@@ -4,9 +4,9 @@ package astcontext
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"go/ast"
+	"go/token"
 	"go/types"
 	"sort"
 )
@@ -45,7 +45,14 @@ type Func struct {
 	// position of the doc comment, only for *ast.FuncDecl
 	Doc *Position `json:"doc,omitempty" vim:"doc,omitempty"`
 
-	node ast.Node // either *ast.FuncDecl or *ast.FuncLit
+	// Metrics of the function, only set by the queries which include them,
+	// see Query.Metrics. They are omitted if the function can't be found in
+	// the current source anymore.
+	Metrics *Metrics `json:"metrics,omitempty" vim:"metrics,omitempty"`
+
+	node    ast.Node // either *ast.FuncDecl or *ast.FuncLit
+	literal bool     // node is a *ast.FuncLit, also set for cached funcs
+	metrics *Metrics // precomputed metrics of cached funcs
 }
 
 // Funcs represents a list of functions
@@ -54,15 +61,13 @@ type Funcs []*Func
 // IsDeclaration returns true if the given function is a function declaration
 // (*ast.FuncDecl)
 func (f *Func) IsDeclaration() bool {
-	_, ok := f.node.(*ast.FuncDecl)
-	return ok
+	return !f.literal
 }
 
 // IsLiteral returns true if the given function is a function literal
 // (*ast.FuncLit)
 func (f *Func) IsLiteral() bool {
-	_, ok := f.node.(*ast.FuncLit)
-	return ok
+	return f.literal
 }
 
 // NewFuncSignature returns a function signature from the given node. Node should
@@ -156,59 +161,73 @@ func NewFuncSignature(node ast.Node) *FuncSignature {
 func (f *Func) String() string {
 	// Print according to GNU error messaging format
 	// https://www.gnu.org/prep/standards/html_node/Errors.html
-	switch x := f.node.(type) {
-	case *ast.FuncDecl:
-		return fmt.Sprintf("%s:%d:%d %s",
-			f.FuncPos.Filename, f.FuncPos.Line, f.FuncPos.Column, x.Name.Name)
-	default:
+	if f.literal {
 		return fmt.Sprintf("%s:%d:%d %s",
 			f.FuncPos.Filename, f.FuncPos.Line, f.FuncPos.Column, "(literal)")
 	}
+
+	return fmt.Sprintf("%s:%d:%d %s",
+		f.FuncPos.Filename, f.FuncPos.Line, f.FuncPos.Column, f.Signature.Name)
 }
 
 // Funcs returns a list of Func's from the parsed source. Func's are sorted
-// according to the order of Go functions in the given source.
+// according to the order of Go functions in the given source. The functions
+// are collected only once, each call returns a new copy of the list.
 func (p *Parser) Funcs() Funcs {
-	var files []*ast.File
-	if p.file != nil {
-		files = append(files, p.file)
-	}
+	p.funcsOnce.Do(func() {
+		p.funcs = p.collectFuncs()
+	})
 
-	if p.pkgs != nil {
-		for _, pkg := range p.pkgs {
-			for _, f := range pkg.Files {
-				files = append(files, f)
-			}
+	funcs := make(Funcs, len(p.funcs))
+	copy(funcs, p.funcs)
+	return funcs
+}
+
+func (p *Parser) collectFuncs() Funcs {
+	if p.cached != nil {
+		var funcs Funcs
+		for _, e := range p.cached {
+			funcs = append(funcs, e.funcs()...)
 		}
+		return funcs
 	}
 
+	// only the files of a cached directory are parsed lazily and can fail
+	files, _ := p.files()
+	return collectFuncs(p.fset, files)
+}
+
+// collectFuncs returns the function declarations and literals of the given
+// files
+func collectFuncs(fset *token.FileSet, files []*ast.File) Funcs {
 	var funcs []*Func
 	inspect := func(n ast.Node) bool {
 		switch x := n.(type) {
 		case *ast.FuncDecl:
 			fn := &Func{
-				FuncPos: ToPosition(p.fset.Position(x.Type.Func)),
+				FuncPos: ToPosition(fset.Position(x.Type.Func)),
 				node:    x,
 			}
 
 			// can be nil for forward declarations
 			if x.Body != nil {
-				fn.Lbrace = ToPosition(p.fset.Position(x.Body.Lbrace))
-				fn.Rbrace = ToPosition(p.fset.Position(x.Body.Rbrace))
+				fn.Lbrace = ToPosition(fset.Position(x.Body.Lbrace))
+				fn.Rbrace = ToPosition(fset.Position(x.Body.Rbrace))
 			}
 
 			if x.Doc != nil {
-				fn.Doc = ToPosition(p.fset.Position(x.Doc.Pos()))
+				fn.Doc = ToPosition(fset.Position(x.Doc.Pos()))
 			}
 
 			fn.Signature = NewFuncSignature(x)
 			funcs = append(funcs, fn)
 		case *ast.FuncLit:
 			fn := &Func{
-				Lbrace:  ToPosition(p.fset.Position(x.Body.Lbrace)),
-				Rbrace:  ToPosition(p.fset.Position(x.Body.Rbrace)),
-				FuncPos: ToPosition(p.fset.Position(x.Type.Func)),
+				Lbrace:  ToPosition(fset.Position(x.Body.Lbrace)),
+				Rbrace:  ToPosition(fset.Position(x.Body.Rbrace)),
+				FuncPos: ToPosition(fset.Position(x.Type.Func)),
 				node:    x,
+				literal: true,
 			}
 
 			fn.Signature = NewFuncSignature(x)
@@ -225,37 +244,19 @@ func (p *Parser) Funcs() Funcs {
 	return funcs
 }
 
-// EnclosingFunc returns the enclosing *Func for the given offset
+// EnclosingFunc returns the enclosing *Func for the given offset. It iterates
+// over all functions, use FuncIndex for repeated lookups.
 func (f Funcs) EnclosingFunc(offset int) (*Func, error) {
 	var encFunc *Func
-
-	// TODO(arslan) this is iterating over all functions. Benchmark it and see
-	// if it's worth it to change it with a more effiecent search function. For
-	// now this is enough for us.
 	for _, fn := range f {
-		// standard function declaration without any docs. Start from the func
-		// keyword
-		start := fn.FuncPos.Offset
-
-		// has a doc, also include it
-		if fn.Doc != nil && fn.Doc.IsValid() {
-			start = fn.Doc.Offset
-		}
-
-		// one liner, start from the beginning to make it easier
-		if fn.FuncPos.Line == fn.Rbrace.Line {
-			start = fn.FuncPos.Offset - fn.FuncPos.Column
-		}
-
-		end := fn.Rbrace.Offset
-
-		if start <= offset && offset <= end {
+		start, end, ok := fn.span()
+		if ok && start <= offset && offset <= end {
 			encFunc = fn
 		}
 	}
 
 	if encFunc == nil {
-		return nil, errors.New("no enclosing functions found")
+		return nil, ErrNoEnclosingFunc
 	}
 
 	return encFunc, nil
@@ -294,7 +295,7 @@ func (f Funcs) PrevFuncShift(offset, shift int) (*Func, error) {
 // value 1 returns c, 2 returns d and anything larger returns an error.
 func (f Funcs) nextFuncShift(offset, shift int) (*Func, error) {
 	if shift < 0 {
-		return nil, errors.New("shift can't be negative")
+		return nil, shiftError(shift, "functions")
 	}
 
 	// find nearest next function
@@ -303,10 +304,11 @@ func (f Funcs) nextFuncShift(offset, shift int) (*Func, error) {
 	})
 
 	if nextIndex >= len(f) {
-		return nil, errors.New("no functions found")
+		return nil, ErrNoFunc
 	}
 
 	fn := f[nextIndex]
+	requested := shift
 
 	// if our position is inside the doc, increase the shift by one to pick up
 	// the next function. This assumes that people editing a doc of a func want
@@ -318,7 +320,10 @@ func (f Funcs) nextFuncShift(offset, shift int) (*Func, error) {
 	}
 
 	if nextIndex+shift >= len(f) {
-		return nil, errors.New("no functions found")
+		if requested == 0 {
+			return nil, ErrNoFunc
+		}
+		return nil, shiftError(requested, "functions")
 	}
 
 	return f[nextIndex+shift], nil
@@ -332,21 +337,23 @@ func (f Funcs) nextFuncShift(offset, shift int) (*Func, error) {
 // error.
 func (f Funcs) prevFuncShift(offset, shift int) (*Func, error) {
 	if shift < 0 {
-		return nil, errors.New("shift can't be negative")
+		return nil, shiftError(shift, "functions")
 	}
 
-	// start from the reverse to get the prev function
-	f.Reserve()
-
+	// the last function before the offset
 	prevIndex := sort.Search(len(f), func(i int) bool {
-		return f[i].FuncPos.Offset < offset
-	})
+		return f[i].FuncPos.Offset >= offset
+	}) - 1
+
+	if prevIndex < 0 {
+		return nil, ErrNoFunc
+	}
 
-	if prevIndex+shift >= len(f) {
-		return nil, errors.New("no functions found")
+	if prevIndex-shift < 0 {
+		return nil, shiftError(shift, "functions")
 	}
 
-	return f[prevIndex+shift], nil
+	return f[prevIndex-shift], nil
 }
 
 func (f Funcs) Len() int      { return len(f) }
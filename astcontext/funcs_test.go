@@ -231,3 +231,26 @@ func TestFuncs_NoFuncs(t *testing.T) {
 
 	}
 }
+
+func TestFunc_String(t *testing.T) {
+	var src = `package foo
+
+func foo() {
+	_ = func() {}
+}
+`
+	parser, err := NewParser(&ParserOptions{Src: []byte(src)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, fn := range parser.Funcs() {
+		got = append(got, fn.String())
+	}
+
+	want := []string{"src.go:3:1 foo", "src.go:4:6 (literal)"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("wrong strings\nwant: %q\ngot:  %q", want, got)
+	}
+}
@@ -0,0 +1,189 @@
+package astcontext
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Import represents a single import spec
+type Import struct {
+	// Path is the unquoted import path
+	Path string `json:"path" vim:"path"`
+
+	// Name is the local name (alias) of the import. Empty if not renamed.
+	Name string `json:"name,omitempty" vim:"name,omitempty"`
+
+	// Group is the index of the blank line separated group the spec belongs
+	// to, starting at 0
+	Group int `json:"group" vim:"group"`
+
+	// Used is true if the package is referenced in the file. Blank and dot
+	// imports are always treated as used.
+	Used bool `json:"used" vim:"used"`
+
+	StartLine int `json:"startLine" vim:"startLine"`
+	StartCol  int `json:"startCol" vim:"startCol"`
+	EndLine   int `json:"endLine" vim:"endLine"`
+	EndCol    int `json:"endCol" vim:"endCol"`
+}
+
+// Imports specifies the result of the "imports" mode
+type Imports struct {
+	// range of the import declarations, from the first "import" keyword to
+	// the end of the last import declaration
+	StartLine int `json:"startLine" vim:"startLine"`
+	StartCol  int `json:"startCol" vim:"startCol"`
+	EndLine   int `json:"endLine" vim:"endLine"`
+	EndCol    int `json:"endCol" vim:"endCol"`
+
+	Specs []Import `json:"specs" vim:"specs"`
+}
+
+// Imports returns the import declarations of the parsed file. It returns an
+// error if the parser doesn't contain a single file or if the file has no
+// imports.
+func (p *Parser) Imports() (*Imports, error) {
+	if p.file == nil {
+		return nil, fmt.Errorf("imports %w", ErrFileRequired)
+	}
+
+	file := p.syntax()
+	var decls []*ast.GenDecl
+	for _, d := range file.Decls {
+		if gd, ok := d.(*ast.GenDecl); ok && gd.Tok == token.IMPORT {
+			decls = append(decls, gd)
+		}
+	}
+
+	if len(decls) == 0 {
+		return nil, ErrNoImports
+	}
+
+	used := usedSelectors(file)
+	tf := p.fset.File(file.Pos())
+	src := p.source(tf)
+
+	start := p.fset.Position(decls[0].Pos())
+	end := p.fset.Position(decls[len(decls)-1].End())
+	imports := &Imports{
+		StartLine: start.Line,
+		StartCol:  start.Column,
+		EndLine:   end.Line,
+		EndCol:    end.Column,
+	}
+
+	group := -1
+	for _, gd := range decls {
+		// every import declaration starts a new group
+		group++
+		prevLine := 0
+
+		for _, spec := range gd.Specs {
+			is := spec.(*ast.ImportSpec)
+
+			start := p.fset.Position(is.Pos())
+			end := p.fset.Position(is.End())
+
+			if prevLine != 0 && blankLineBetween(tf, src, prevLine, start.Line) {
+				group++
+			}
+			prevLine = end.Line
+
+			importPath, err := strconv.Unquote(is.Path.Value)
+			if err != nil {
+				importPath = is.Path.Value
+			}
+
+			imp := Import{
+				Path:      importPath,
+				Group:     group,
+				StartLine: start.Line,
+				StartCol:  start.Column,
+				EndLine:   end.Line,
+				EndCol:    end.Column,
+			}
+
+			name := importName(importPath)
+			if is.Name != nil {
+				imp.Name = is.Name.Name
+				name = is.Name.Name
+			}
+
+			switch name {
+			case "_", ".":
+				imp.Used = true
+			default:
+				imp.Used = used[name]
+			}
+
+			imports.Specs = append(imports.Specs, imp)
+		}
+	}
+
+	return imports, nil
+}
+
+// blankLineBetween reports whether there is a blank line between the lines
+// from and to, exclusive. Lines of comments aren't blank, like gofmt they
+// don't separate groups of imports. Without the source every line in between
+// is assumed to be blank.
+func blankLineBetween(tf *token.File, src []byte, from, to int) bool {
+	if src == nil {
+		return to > from+1
+	}
+
+	for line := from + 1; line < to; line++ {
+		start := tf.Offset(tf.LineStart(line))
+		end := tf.Offset(tf.LineStart(line + 1))
+		if isBlank(src[start:end]) {
+			return true
+		}
+	}
+	return false
+}
+
+// usedSelectors returns the set of identifiers that are used as the
+// qualifier of a selector expression, i.e: "fmt" in "fmt.Println".
+func usedSelectors(file *ast.File) map[string]bool {
+	used := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+
+		if id, ok := sel.X.(*ast.Ident); ok {
+			used[id.Name] = true
+		}
+		return true
+	})
+	return used
+}
+
+// importName returns the assumed package name of the given import path. The
+// rules are the same as goimports uses for paths it can't resolve, i.e:
+// "gopkg.in/yaml.v2" is "yaml" and "github.com/foo/bar/v2" is "bar".
+func importName(importPath string) string {
+	base := path.Base(importPath)
+	if strings.HasPrefix(base, "v") {
+		if _, err := strconv.Atoi(base[1:]); err == nil {
+			dir := path.Dir(importPath)
+			if dir != "." {
+				base = path.Base(dir)
+			}
+		}
+	}
+
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
@@ -0,0 +1,122 @@
+package astcontext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestImports(t *testing.T) {
+	var src = `package main
+
+import (
+	"fmt"
+	"os"
+
+	yaml "gopkg.in/yaml.v2"
+	_ "net/http/pprof"
+	"github.com/fatih/color/v2"
+)
+
+import "strings"
+
+func main() {
+	fmt.Println(yaml.Marshal, color.New)
+}
+`
+	opts := &ParserOptions{Src: []byte(src)}
+	parser, err := NewParser(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := parser.Run(&Query{Mode: "imports"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imports := out.Imports
+	if imports.StartLine != 3 || imports.EndLine != 12 {
+		t.Errorf("wrong import block lines, want: 3-12, got: %d-%d",
+			imports.StartLine, imports.EndLine)
+	}
+
+	want := []Import{
+		{Path: "fmt", Group: 0, Used: true, StartLine: 4, StartCol: 2, EndLine: 4, EndCol: 7},
+		{Path: "os", Group: 0, Used: false, StartLine: 5, StartCol: 2, EndLine: 5, EndCol: 6},
+		{Path: "gopkg.in/yaml.v2", Name: "yaml", Group: 1, Used: true, StartLine: 7, StartCol: 2, EndLine: 7, EndCol: 25},
+		{Path: "net/http/pprof", Name: "_", Group: 1, Used: true, StartLine: 8, StartCol: 2, EndLine: 8, EndCol: 20},
+		{Path: "github.com/fatih/color/v2", Group: 1, Used: true, StartLine: 9, StartCol: 2, EndLine: 9, EndCol: 29},
+		{Path: "strings", Group: 2, Used: false, StartLine: 12, StartCol: 8, EndLine: 12, EndCol: 17},
+	}
+
+	if !reflect.DeepEqual(imports.Specs, want) {
+		t.Errorf("wrong imports:\nwant: %+v\ngot:  %+v", want, imports.Specs)
+	}
+}
+
+func TestImports_CommentedSpecs(t *testing.T) {
+	var src = `package main
+
+import (
+	"fmt"
+	// "log"
+	"os"
+	/*
+		"io"
+	*/
+	"strings"
+
+	// doc of sync
+	"sync"
+)
+`
+	opts := &ParserOptions{Src: []byte(src), Comments: true}
+	parser, err := NewParser(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imports, err := parser.Imports()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var groups []int
+	for _, spec := range imports.Specs {
+		groups = append(groups, spec.Group)
+	}
+
+	want := []int{0, 0, 0, 1}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("wrong groups, want: %v, got: %v", want, groups)
+	}
+}
+
+func TestImports_NoImports(t *testing.T) {
+	opts := &ParserOptions{Src: []byte("package main")}
+	parser, err := NewParser(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = parser.Run(&Query{Mode: "imports"})
+	if !errorContains(err, "no imports found") {
+		t.Errorf("wrong error, got: %v", err)
+	}
+}
+
+func TestImportName(t *testing.T) {
+	cases := map[string]string{
+		"fmt":                        "fmt",
+		"net/http":                   "http",
+		"gopkg.in/yaml.v2":           "yaml",
+		"github.com/fatih/color/v2":  "color",
+		"github.com/mattn/go-isatty": "isatty",
+	}
+
+	for path, want := range cases {
+		if got := importName(path); got != want {
+			t.Errorf("importName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package astcontext
+
+import (
+	"sort"
+)
+
+// FuncIndex is an index of functions for fast lookups by offset. It answers
+// enclosing, next, previous and intersecting queries in logarithmic time,
+// which matters for large generated files with thousands of functions. The
+// index is immutable and safe for concurrent use.
+type FuncIndex struct {
+	// funcs are all functions in the order of Funcs
+	funcs Funcs
+
+	// decls are the function declarations, used for next and prev
+	decls Funcs
+
+	// byStart are the indexes of funcs with a body, sorted by the start of
+	// their span, starts are the corresponding starts
+	byStart []int
+	starts  []int
+
+	// coords are the sorted boundaries of the elementary segments of the
+	// tree. Leaf k covers the offsets [coords[k], coords[k+1]).
+	coords []int
+
+	// tree is a segment tree over the elementary segments. Each node lists
+	// the indexes of the funcs whose span covers the node's segments, but not
+	// the ones of its parent, in increasing order.
+	tree [][]int
+	size int
+}
+
+// NewFuncIndex returns an index of the given functions
+func NewFuncIndex(funcs Funcs) *FuncIndex {
+	x := &FuncIndex{
+		funcs: funcs,
+		decls: funcs.Declarations(),
+	}
+
+	// the indexes of the funcs with a body, in increasing order
+	var bodies []int
+
+	type span struct{ start, end int }
+	spans := make([]span, len(funcs))
+	for i, fn := range funcs {
+		start, end, ok := fn.span()
+		if !ok {
+			continue
+		}
+		spans[i] = span{start, end + 1} // half open
+		bodies = append(bodies, i)
+		x.coords = append(x.coords, start, end+1)
+	}
+
+	x.byStart = append([]int(nil), bodies...)
+	sort.SliceStable(x.byStart, func(i, j int) bool {
+		return spans[x.byStart[i]].start < spans[x.byStart[j]].start
+	})
+
+	x.starts = make([]int, len(x.byStart))
+	for i, j := range x.byStart {
+		x.starts[i] = spans[j].start
+	}
+
+	sort.Ints(x.coords)
+	x.coords = uniqueInts(x.coords)
+
+	leaves := len(x.coords) - 1
+	if leaves <= 0 {
+		return x
+	}
+
+	x.size = 1
+	for x.size < leaves {
+		x.size *= 2
+	}
+	x.tree = make([][]int, 2*x.size)
+
+	// funcs are inserted in increasing order, so the lists stay sorted
+	for _, i := range bodies {
+		l := sort.SearchInts(x.coords, spans[i].start) + x.size
+		r := sort.SearchInts(x.coords, spans[i].end) + x.size
+		for ; l < r; l, r = l/2, r/2 {
+			if l&1 == 1 {
+				x.tree[l] = append(x.tree[l], i)
+				l++
+			}
+			if r&1 == 1 {
+				r--
+				x.tree[r] = append(x.tree[r], i)
+			}
+		}
+	}
+
+	return x
+}
+
+// FuncIndex returns the index of the functions of the parsed source. The
+// index is built only once and is reused by all following queries.
+func (p *Parser) FuncIndex() *FuncIndex {
+	p.indexOnce.Do(func() {
+		p.index = NewFuncIndex(p.Funcs())
+	})
+	return p.index
+}
+
+// EnclosingFunc returns the innermost *Func enclosing the given offset. It
+// returns the same function as Funcs.EnclosingFunc.
+func (x *FuncIndex) EnclosingFunc(offset int) (*Func, error) {
+	best := -1
+	x.stab(offset, func(list []int) {
+		if last := list[len(list)-1]; last > best {
+			best = last
+		}
+	})
+
+	if best < 0 {
+		return nil, ErrNoEnclosingFunc
+	}
+
+	return x.funcs[best], nil
+}
+
+// NextFuncShift returns the nearest next function declaration for the given
+// offset, shifted by shift. See Funcs.NextFuncShift.
+func (x *FuncIndex) NextFuncShift(offset, shift int) (*Func, error) {
+	return x.decls.nextFuncShift(offset, shift)
+}
+
+// PrevFuncShift returns the nearest previous function declaration for the
+// given offset, shifted by shift. See Funcs.PrevFuncShift.
+func (x *FuncIndex) PrevFuncShift(offset, shift int) (*Func, error) {
+	return x.decls.prevFuncShift(offset, shift)
+}
+
+// Intersecting returns the functions whose span intersects with the given
+// inclusive range of offsets, in the order of Funcs. A function intersects
+// if it encloses the start or begins inside the range.
+func (x *FuncIndex) Intersecting(start, end int) Funcs {
+	if end < start {
+		return nil
+	}
+
+	var indexes []int
+	x.stab(start, func(list []int) {
+		indexes = append(indexes, list...)
+	})
+
+	// the functions which begin inside (start, end], the ones beginning at
+	// start enclose it and are already added
+	from := sort.SearchInts(x.starts, start+1)
+	to := sort.SearchInts(x.starts, end+1)
+	indexes = append(indexes, x.byStart[from:to]...)
+
+	sort.Ints(indexes)
+	funcs := make(Funcs, 0, len(indexes))
+	for _, i := range indexes {
+		funcs = append(funcs, x.funcs[i])
+	}
+	return funcs
+}
+
+// stab calls fn with the non empty lists of all nodes covering the given
+// offset, from the leaf to the root
+func (x *FuncIndex) stab(offset int, fn func([]int)) {
+	if x.tree == nil || offset < x.coords[0] || offset >= x.coords[len(x.coords)-1] {
+		return
+	}
+
+	// the last boundary which is not greater than offset
+	k := sort.Search(len(x.coords), func(i int) bool { return x.coords[i] > offset }) - 1
+	for n := k + x.size; n > 0; n /= 2 {
+		if len(x.tree[n]) > 0 {
+			fn(x.tree[n])
+		}
+	}
+}
+
+// span returns the inclusive range of offsets which belong to the function,
+// as used by EnclosingFunc. It's false for functions without a body.
+func (f *Func) span() (start, end int, ok bool) {
+	if f.FuncPos == nil || f.Rbrace == nil {
+		return 0, 0, false
+	}
+
+	// standard function declaration without any docs. Start from the func
+	// keyword
+	start = f.FuncPos.Offset
+
+	// has a doc, also include it
+	if f.Doc != nil && f.Doc.IsValid() {
+		start = f.Doc.Offset
+	}
+
+	// one liner, start from the beginning to make it easier
+	if f.FuncPos.Line == f.Rbrace.Line {
+		start = f.FuncPos.Offset - f.FuncPos.Column
+	}
+
+	return start, f.Rbrace.Offset, true
+}
+
+// uniqueInts removes the duplicates of the sorted slice in place
+func uniqueInts(s []int) []int {
+	if len(s) == 0 {
+		return s
+	}
+
+	out := s[:1]
+	for _, v := range s[1:] {
+		if v != out[len(out)-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
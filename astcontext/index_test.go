@@ -0,0 +1,189 @@
+package astcontext
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// generateSource returns a Go source with n function declarations. Every
+// declaration has a doc comment and every third one contains nested function
+// literals, every fifth one is a one liner.
+func generateSource(n int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("package gen\n\n")
+	for i := 0; i < n; i++ {
+		switch {
+		case i%5 == 0:
+			fmt.Fprintf(&buf, "func F%d() int { return func() int { return %d }() }\n\n", i, i)
+		case i%3 == 0:
+			fmt.Fprintf(&buf, "// F%d is generated\nfunc F%d(x int) int {\n\tf := func(y int) int {\n\t\tg := func() int { return y }\n\t\treturn g() + x\n\t}\n\treturn f(%d)\n}\n\n", i, i, i)
+		default:
+			fmt.Fprintf(&buf, "// F%d is generated\nfunc F%d(x int) int {\n\treturn x + %d\n}\n\n", i, i, i)
+		}
+	}
+	buf.WriteString("func forward()\n")
+	return buf.Bytes()
+}
+
+func TestFuncIndex(t *testing.T) {
+	src := generateSource(300)
+	parser, err := NewParser(&ParserOptions{Src: src, Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	funcs := parser.Funcs()
+	decls := funcs.Declarations()
+	index := parser.FuncIndex()
+
+	for offset := -1; offset <= len(src)+1; offset++ {
+		want, wantErr := funcs.EnclosingFunc(offset)
+		got, err := index.EnclosingFunc(offset)
+		if got != want || !sameError(err, wantErr) {
+			t.Fatalf("EnclosingFunc(%d)\nwant: %v, %v\ngot:  %v, %v", offset, want, wantErr, got, err)
+		}
+
+		for shift := 0; shift < 3; shift++ {
+			want, wantErr := decls.NextFuncShift(offset, shift)
+			got, err := index.NextFuncShift(offset, shift)
+			if got != want || !sameError(err, wantErr) {
+				t.Fatalf("NextFuncShift(%d, %d)\nwant: %v, %v\ngot:  %v, %v", offset, shift, want, wantErr, got, err)
+			}
+
+			want, wantErr = decls.PrevFuncShift(offset, shift)
+			got, err = index.PrevFuncShift(offset, shift)
+			if got != want || !sameError(err, wantErr) {
+				t.Fatalf("PrevFuncShift(%d, %d)\nwant: %v, %v\ngot:  %v, %v", offset, shift, want, wantErr, got, err)
+			}
+		}
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		start := rnd.Intn(len(src)+20) - 10
+		end := start + rnd.Intn(400) - 20
+
+		var want Funcs
+		for _, fn := range funcs {
+			s, e, ok := fn.span()
+			if ok && s <= end && start <= e && start <= end {
+				want = append(want, fn)
+			}
+		}
+
+		got := index.Intersecting(start, end)
+		if len(got) == 0 && len(want) == 0 {
+			continue
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("Intersecting(%d, %d)\nwant: %v\ngot:  %v", start, end, want, got)
+		}
+	}
+}
+
+func sameError(a, b error) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Error() == b.Error()
+}
+
+func TestFuncIndex_Empty(t *testing.T) {
+	index := NewFuncIndex(nil)
+
+	if _, err := index.EnclosingFunc(0); !errors.Is(err, ErrNoEnclosingFunc) {
+		t.Errorf("wrong error: %v", err)
+	}
+
+	if _, err := index.NextFuncShift(0, 0); !errors.Is(err, ErrNoFunc) {
+		t.Errorf("wrong error: %v", err)
+	}
+
+	if funcs := index.Intersecting(0, 100); len(funcs) != 0 {
+		t.Errorf("expected no functions, got: %v", funcs)
+	}
+}
+
+func benchmarkParser(b *testing.B, n int) (*Parser, int) {
+	src := generateSource(n)
+	parser, err := NewParser(&ParserOptions{Src: src, Comments: true})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return parser, len(src)
+}
+
+func BenchmarkEnclosingFunc_Linear(b *testing.B) {
+	parser, size := benchmarkParser(b, 10000)
+	funcs := parser.Funcs()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		funcs.EnclosingFunc(i * 7919 % size)
+	}
+}
+
+func BenchmarkEnclosingFunc_Index(b *testing.B) {
+	parser, size := benchmarkParser(b, 10000)
+	index := parser.FuncIndex()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.EnclosingFunc(i * 7919 % size)
+	}
+}
+
+func BenchmarkNextFunc_Declarations(b *testing.B) {
+	parser, size := benchmarkParser(b, 10000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parser.Funcs().Declarations().NextFuncShift(i*7919%size, 1)
+	}
+}
+
+func BenchmarkNextFunc_Index(b *testing.B) {
+	parser, size := benchmarkParser(b, 10000)
+	index := parser.FuncIndex()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.NextFuncShift(i*7919%size, 1)
+	}
+}
+
+func BenchmarkIntersecting(b *testing.B) {
+	parser, size := benchmarkParser(b, 10000)
+	index := parser.FuncIndex()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		start := i * 7919 % size
+		index.Intersecting(start, start+2000)
+	}
+}
+
+func BenchmarkNewFuncIndex(b *testing.B) {
+	parser, _ := benchmarkParser(b, 10000)
+	funcs := parser.Funcs()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewFuncIndex(funcs)
+	}
+}
+
+func BenchmarkRun_Enclosing(b *testing.B) {
+	parser, size := benchmarkParser(b, 10000)
+	parser.FuncIndex()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parser.Run(&Query{Mode: "enclosing", Offset: i * 7919 % size})
+	}
+}
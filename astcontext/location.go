@@ -0,0 +1,127 @@
+package astcontext
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Location is a single item of a result, used by the plain text output
+// formats. It's written as "file:line:col: text", the GNU error messaging
+// format, so it can be used with grep, fzf, Vim's quickfix list or Emacs'
+// compilation-mode.
+type Location struct {
+	Filename string
+	Line     int
+	Col      int
+	Text     string
+}
+
+// String returns the location in the GNU error messaging format
+// https://www.gnu.org/prep/standards/html_node/Errors.html
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", l.Filename, l.Line, l.Col, l.Text)
+}
+
+// Locations returns the items of the result, one for each declaration,
+// import, context segment, fold, annotation, directive, doc stub, ranked
+// function or exit point and a single one for a function or comment. Items
+// which don't have a filename, such as imports and folds, get the given
+// filename.
+func (r *Result) Locations(filename string) []Location {
+	var locs []Location
+	add := func(file string, line, col int, text string) {
+		if file == "" {
+			file = filename
+		}
+		locs = append(locs, Location{Filename: file, Line: line, Col: col, Text: text})
+	}
+
+	if r.Func != nil {
+		text := "(literal)"
+		if r.Func.Signature != nil {
+			text = r.Func.Signature.Full
+		}
+		add(r.Func.FuncPos.Filename, r.Func.FuncPos.Line, r.Func.FuncPos.Column, text)
+	}
+
+	if r.Comment != nil {
+		add("", r.Comment.StartLine, r.Comment.StartCol, "comment")
+	}
+
+	decls := r.Decls
+	if r.Symbols != nil {
+		decls = r.Symbols.Decls
+	}
+	for _, d := range decls {
+		add(d.Filename, d.Line, d.Col, d.Full)
+	}
+
+	if r.Imports != nil {
+		for _, spec := range r.Imports.Specs {
+			text := "import " + strconv.Quote(spec.Path)
+			if spec.Name != "" {
+				text = "import " + spec.Name + " " + strconv.Quote(spec.Path)
+			}
+			add("", spec.StartLine, spec.StartCol, text)
+		}
+	}
+
+	if r.Context != nil {
+		for _, s := range r.Context.Segments {
+			add("", s.StartLine, s.StartCol, s.Name)
+		}
+	}
+
+	for _, f := range r.Folds {
+		add("", f.StartLine, f.StartCol, f.Kind)
+	}
+
+	for _, t := range r.Todos {
+		text := t.Marker
+		if t.Author != "" {
+			text += "(" + t.Author + ")"
+		}
+		if t.Text != "" {
+			text += ": " + t.Text
+		}
+		add(t.Filename, t.Line, t.Col, text)
+	}
+
+	for _, d := range r.Directives {
+		add(d.Filename, d.Line, d.Col, d.Text)
+	}
+
+	for _, f := range r.Funcs {
+		text := f.Signature.Full
+		if m := f.Metrics; m != nil {
+			text += fmt.Sprintf(" cyclomatic=%d nesting=%d lines=%d statements=%d returns=%d",
+				m.Cyclomatic, m.Nesting, m.Lines, m.Statements, m.Returns)
+		}
+		add(f.FuncPos.Filename, f.FuncPos.Line, f.FuncPos.Column, text)
+	}
+
+	for _, e := range r.Exits {
+		add(e.Pos.Filename, e.Pos.Line, e.Pos.Column, e.Text)
+	}
+
+	for _, s := range r.DocStubs {
+		add(s.Decl.Filename, s.Decl.Line, s.Decl.Col, s.Decl.Full)
+	}
+
+	return locs
+}
+
+// Locations returns the syntax errors of a parse error. Other errors don't
+// have a position and return no locations.
+func (r *ErrorResult) Locations() []Location {
+	var locs []Location
+	for _, e := range r.Errors {
+		locs = append(locs, Location{
+			Filename: e.Filename,
+			Line:     e.Line,
+			Col:      e.Column,
+			Text:     e.Msg,
+		})
+	}
+	return locs
+}
@@ -0,0 +1,94 @@
+package astcontext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResult_Locations(t *testing.T) {
+	var src = `package main
+
+import (
+	"fmt"
+	str "strings"
+)
+
+type T struct{}
+
+func (T) Foo() {
+	fmt.Println(str.ToUpper("foo"))
+}
+`
+	parser, err := NewParser(&ParserOptions{Src: []byte(src)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		query *Query
+		want  []string
+	}{
+		{
+			query: &Query{Mode: "decls", Includes: []string{"type", "func"}},
+			want: []string{
+				"src.go:8:6: type T struct{}",
+				"src.go:10:1: func (T) Foo()",
+			},
+		},
+		{
+			query: &Query{Mode: "enclosing", Offset: 90},
+			want:  []string{"src.go:10:1: func (T) Foo()"},
+		},
+		{
+			query: &Query{Mode: "imports"},
+			want: []string{
+				`main.go:4:2: import "fmt"`,
+				`main.go:5:2: import str "strings"`,
+			},
+		},
+		{
+			query: &Query{Mode: "context", Offset: 90},
+			want: []string{
+				"main.go:1:1: pkg main",
+				"main.go:8:6: type T",
+				"main.go:10:1: func (T) Foo",
+			},
+		},
+		{
+			query: &Query{Mode: "folds"},
+			want: []string{
+				"main.go:3:1: imports",
+				"main.go:10:16: func",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		res, err := parser.Run(tc.query)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.query.Mode, err)
+		}
+
+		var got []string
+		for _, l := range res.Locations("main.go") {
+			got = append(got, l.String())
+		}
+
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: wrong locations\nwant: %q\ngot:  %q", tc.query.Mode, tc.want, got)
+		}
+	}
+}
+
+func TestErrorResult_Locations(t *testing.T) {
+	_, err := NewParser(&ParserOptions{Src: []byte("package main\n\nfunc (")})
+
+	got := NewErrorResult(err).Locations()
+	if len(got) == 0 || got[0].Filename != "src.go" || got[0].Line != 3 {
+		t.Errorf("wrong locations of the parse error: %v", got)
+	}
+
+	if got := NewErrorResult(ErrNoFunc).Locations(); len(got) != 0 {
+		t.Errorf("an error without position should have no locations, got: %v", got)
+	}
+}
@@ -0,0 +1,240 @@
+package astcontext
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+)
+
+// Metrics are the metrics of a single function, used to find complex
+// functions. The statements of nested function literals belong to the
+// enclosing function as well, except for their return statements.
+type Metrics struct {
+	// Lines is the number of lines from the func keyword to the closing
+	// brace
+	Lines int `json:"lines" vim:"lines"`
+
+	// Statements is the number of statements. Blocks, labels and case
+	// clauses are not counted.
+	Statements int `json:"statements" vim:"statements"`
+
+	// Cyclomatic is the cyclomatic complexity: one plus the number of if,
+	// for and range statements, non default case clauses and && and ||
+	// operators
+	Cyclomatic int `json:"cyclomatic" vim:"cyclomatic"`
+
+	// Nesting is the maximum nesting depth of if, for, range, switch and
+	// select statements and function literals. An "else if" is on the same
+	// depth as its if statement.
+	Nesting int `json:"nesting" vim:"nesting"`
+
+	// Returns is the number of return statements of the function
+	Returns int `json:"returns" vim:"returns"`
+
+	// Params and Results are the number of parameters and results. Each
+	// name of a grouped parameter counts, the receiver doesn't.
+	Params  int `json:"params" vim:"params"`
+	Results int `json:"results" vim:"results"`
+}
+
+// NewMetrics returns the metrics of the given node, which should be of type
+// *ast.FuncDecl or *ast.FuncLit
+func NewMetrics(fset *token.FileSet, node ast.Node) *Metrics {
+	var typ *ast.FuncType
+	var body *ast.BlockStmt
+	switch x := node.(type) {
+	case *ast.FuncDecl:
+		typ, body = x.Type, x.Body
+	case *ast.FuncLit:
+		typ, body = x.Type, x.Body
+	default:
+		return &Metrics{}
+	}
+
+	m := &Metrics{
+		Lines:      fset.Position(node.End()).Line - fset.Position(typ.Pos()).Line + 1,
+		Cyclomatic: 1,
+		Params:     fieldCount(typ.Params),
+		Results:    fieldCount(typ.Results),
+	}
+
+	if body != nil {
+		ast.Walk(metricsVisitor{m: m}, body)
+	}
+	return m
+}
+
+// fieldCount returns the number of names of the given field list. Unnamed
+// fields count as one.
+func fieldCount(list *ast.FieldList) int {
+	if list == nil {
+		return 0
+	}
+
+	n := 0
+	for _, field := range list.List {
+		if len(field.Names) == 0 {
+			n++
+			continue
+		}
+		n += len(field.Names)
+	}
+	return n
+}
+
+// metricsVisitor collects the metrics of a function body. Depth is the
+// nesting depth of the visited nodes.
+type metricsVisitor struct {
+	m       *Metrics
+	depth   int
+	literal bool
+}
+
+// nested returns the visitor for the children of a nesting node
+func (v metricsVisitor) nested() metricsVisitor {
+	v.depth++
+	if v.depth > v.m.Nesting {
+		v.m.Nesting = v.depth
+	}
+	return v
+}
+
+func (v metricsVisitor) Visit(n ast.Node) ast.Visitor {
+	if n == nil {
+		return nil
+	}
+
+	if _, ok := n.(ast.Stmt); ok {
+		switch n.(type) {
+		case *ast.BlockStmt, *ast.LabeledStmt, *ast.CaseClause, *ast.CommClause, *ast.EmptyStmt:
+		default:
+			v.m.Statements++
+		}
+	}
+
+	switch x := n.(type) {
+	case *ast.IfStmt:
+		v.m.Cyclomatic++
+
+		inner := v.nested()
+		if x.Init != nil {
+			ast.Walk(inner, x.Init)
+		}
+		ast.Walk(inner, x.Cond)
+		ast.Walk(inner, x.Body)
+
+		switch e := x.Else.(type) {
+		case *ast.IfStmt:
+			// else if
+			ast.Walk(v, e)
+		case *ast.BlockStmt:
+			ast.Walk(inner, e)
+		}
+		return nil
+	case *ast.ForStmt, *ast.RangeStmt:
+		v.m.Cyclomatic++
+		return v.nested()
+	case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+		return v.nested()
+	case *ast.CaseClause:
+		if x.List != nil {
+			v.m.Cyclomatic++
+		}
+	case *ast.CommClause:
+		if x.Comm != nil {
+			v.m.Cyclomatic++
+		}
+	case *ast.BinaryExpr:
+		if x.Op == token.LAND || x.Op == token.LOR {
+			v.m.Cyclomatic++
+		}
+	case *ast.ReturnStmt:
+		if !v.literal {
+			v.m.Returns++
+		}
+	case *ast.FuncLit:
+		inner := v.nested()
+		inner.literal = true
+		return inner
+	}
+
+	return v
+}
+
+// RankedFuncs returns the function declarations of the parsed files with
+// their metrics, the most complex ones first. Functions are ranked by their
+// cyclomatic complexity, nesting depth and length. Functions without metrics
+// are ranked last.
+func (p *Parser) RankedFuncs() Funcs {
+	funcs := p.Funcs().Declarations()
+	for i, fn := range funcs {
+		funcs[i] = p.withMetrics(fn)
+	}
+
+	sort.SliceStable(funcs, func(i, j int) bool {
+		a, b := funcs[i].Metrics, funcs[j].Metrics
+		switch {
+		case a == nil || b == nil:
+			return a != nil && b == nil
+		case a.Cyclomatic != b.Cyclomatic:
+			return a.Cyclomatic > b.Cyclomatic
+		case a.Nesting != b.Nesting:
+			return a.Nesting > b.Nesting
+		default:
+			return a.Lines > b.Lines
+		}
+	})
+	return funcs
+}
+
+// withMetrics returns a copy of the function with its metrics. Functions
+// moved by Edit don't have a node, their node is looked up in the current
+// AST. The metrics are omitted if the node can't be found, i.e: if the edited
+// source doesn't parse anymore.
+func (p *Parser) withMetrics(fn *Func) *Func {
+	m := fn.metrics
+	if m == nil {
+		node := fn.node
+		if node == nil {
+			node = p.funcNode(fn)
+		}
+		if node != nil {
+			m = NewMetrics(p.fset, node)
+		}
+	}
+
+	f := *fn
+	f.Metrics = m
+	return &f
+}
+
+// funcNode returns the *ast.FuncDecl or *ast.FuncLit of the given function
+// in the parsed file, nil if it can't be found
+func (p *Parser) funcNode(fn *Func) ast.Node {
+	if p.file == nil {
+		return nil
+	}
+
+	file := p.syntax()
+	pos := p.fset.File(file.Pos()).Pos(fn.FuncPos.Offset)
+
+	var node ast.Node
+	ast.Inspect(file, func(n ast.Node) bool {
+		if node != nil {
+			return false
+		}
+
+		switch x := n.(type) {
+		case *ast.FuncDecl:
+			if x.Type.Func == pos {
+				node = x
+			}
+		case *ast.FuncLit:
+			if x.Type.Func == pos {
+				node = x
+			}
+		}
+		return true
+	})
+	return node
+}
@@ -0,0 +1,136 @@
+package astcontext
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+const metricsSrc = `package main
+
+func Simple(a, b int, c string) (int, error) {
+	return a + b, nil
+}
+
+func Complex(xs []int) int {
+	n := 0
+	for _, x := range xs {
+		if x > 0 && x < 10 {
+			n++
+		} else if x > 100 || x < -100 {
+			n--
+		} else {
+			switch x {
+			case 1, 2:
+				return 1
+			case 3:
+			default:
+			}
+		}
+	}
+
+	f := func() int {
+		if n > 0 {
+			return n
+		}
+		return 0
+	}
+
+	select {
+	case <-make(chan int):
+	default:
+	}
+
+	return f()
+}
+
+func Forward() int
+`
+
+func TestNewMetrics(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "src.go", metricsSrc, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]Metrics{
+		"Simple": {Lines: 3, Statements: 1, Cyclomatic: 1, Returns: 1, Params: 3, Results: 2},
+		"Complex": {
+			Lines:      31,
+			Statements: 15,
+			Cyclomatic: 10,
+			Nesting:    3,
+			Returns:    2,
+			Params:     1,
+			Results:    1,
+		},
+		"Forward": {Lines: 1, Cyclomatic: 1, Results: 1},
+	}
+
+	for _, decl := range file.Decls {
+		fn := decl.(*ast.FuncDecl)
+		if got := *NewMetrics(fset, fn); got != want[fn.Name.Name] {
+			t.Errorf("%s: wrong metrics\nwant: %+v\ngot:  %+v", fn.Name.Name, want[fn.Name.Name], got)
+		}
+	}
+}
+
+func TestParser_RankedFuncs(t *testing.T) {
+	parser, err := NewParser(&ParserOptions{Src: []byte(metricsSrc)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := parser.Run(&Query{Mode: "metrics"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, fn := range res.Funcs {
+		names = append(names, fn.Signature.Name)
+	}
+	if strings.Join(names, ",") != "Complex,Simple,Forward" {
+		t.Errorf("wrong ranking: %q", names)
+	}
+
+	// metrics are opt-in for the other modes
+	offset := strings.Index(metricsSrc, "n := 0")
+	res, err = parser.Run(&Query{Mode: "enclosing", Offset: offset})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Func.Metrics != nil {
+		t.Errorf("want no metrics, got: %+v", res.Func.Metrics)
+	}
+
+	res, err = parser.Run(&Query{Mode: "enclosing", Offset: offset, Metrics: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Func.Metrics == nil || res.Func.Metrics.Cyclomatic != 10 {
+		t.Errorf("wrong metrics: %+v", res.Func.Metrics)
+	}
+
+	// functions moved by an edit don't have a node anymore
+	if err := parser.Edit(TextEdit{Start: 14, End: 14, Text: "\n"}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = parser.Run(&Query{Mode: "enclosing", Offset: offset + 1, Metrics: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Func.Metrics == nil || res.Func.Metrics.Cyclomatic != 10 {
+		t.Errorf("wrong metrics after edit: %+v", res.Func.Metrics)
+	}
+
+	// functions which can't be found have no metrics instead of zero ones
+	fn := parser.withMetrics(&Func{FuncPos: &Position{Offset: 0}})
+	if fn.Metrics != nil {
+		t.Errorf("want no metrics for a missing function, got: %+v", fn.Metrics)
+	}
+}
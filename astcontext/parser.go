@@ -1,10 +1,11 @@
 package astcontext
 
 import (
-	"errors"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"os"
+	"sync"
 )
 
 // ParserOptions defines the options that changes the Parser's behavior
@@ -15,11 +16,21 @@ type ParserOptions struct {
 	// Dir defines the directory to be parsed
 	Dir string
 
-	// Src defines the source to be parsed
+	// Build enables build constraint aware parsing of Dir. If nil all Go
+	// files of the directory are parsed, regardless of their constraints.
+	Build *BuildOptions
+
+	// Src defines the source to be parsed. If File is set too, Src is used as
+	// the content of File instead of reading it from disk.
 	Src []byte
 
 	// If enabled parses the comments too
 	Comments bool
+
+	// CacheDir enables the cache of Dir. The functions and types of every
+	// file are stored in CacheDir and reused as long as the file doesn't
+	// change, so unchanged files aren't parsed again.
+	CacheDir string
 }
 
 // Parser defines the customized parser
@@ -27,11 +38,45 @@ type Parser struct {
 	// fset is the default fileset that is passed to the internal parser
 	fset *token.FileSet
 
-	// file contains the parsed file
-	file *ast.File
+	// file contains the parsed file. After an incremental Edit it's stale
+	// and parsed again by syntax() on the first access.
+	file  *ast.File
+	stale bool
+
+	// src and filename are the source of a single file, used by Edit
+	src      []byte
+	filename string
+
+	// decls and comments are the spans of the top level declarations and
+	// comment groups of a single file, built on demand and kept up to date
+	// by Edit
+	decls    []declSpan
+	comments []commentSpan
+
+	// dir is the parsed directory, the root of the "symbols" and recursive
+	// "todos" modes
+	dir string
+
+	// pkgs contains the parsed packages. If the declarations are loaded
+	// from the cache, the packages are parsed lazily by packages()
+	pkgs     map[string]*ast.Package
+	pkgsErr  error
+	pkgsOnce sync.Once
+	mode     parser.Mode
 
-	// pkgs contains the parsed packages
-	pkgs map[string]*ast.Package
+	// cached contains the cache entries of the files of a directory
+	cached []*cacheEntry
+
+	// funcs and types are computed once on the first call to Funcs() and
+	// Types() and are reused by all following queries
+	funcsOnce sync.Once
+	funcs     Funcs
+	typesOnce sync.Once
+	types     Types
+
+	// index is built once on the first call to FuncIndex()
+	indexOnce sync.Once
+	index     *FuncIndex
 }
 
 // NewParser creates a new Parser reference from the given options
@@ -42,28 +87,73 @@ func NewParser(opts *ParserOptions) (*Parser, error) {
 	}
 
 	fset := token.NewFileSet()
-	p := &Parser{fset: fset}
+	p := &Parser{fset: fset, mode: mode}
 	var err error
 
 	switch {
 	case opts.File != "":
-		p.file, err = parser.ParseFile(fset, opts.File, nil, mode)
+		src := opts.Src
+		if src == nil {
+			src, err = os.ReadFile(opts.File)
+			if err != nil {
+				return nil, toParseError(err)
+			}
+		}
+
+		p.src, p.filename = src, opts.File
+		p.file, err = parser.ParseFile(fset, opts.File, src, mode)
 		if err != nil {
-			return nil, err
+			return nil, toParseError(err)
+		}
+	case opts.Dir != "" && opts.CacheDir != "":
+		p.dir = opts.Dir
+		p.cached, err = loadDir(opts.CacheDir, opts.Dir, opts.Build, mode)
+		if err != nil {
+			return nil, toParseError(err)
 		}
 	case opts.Dir != "":
-		p.pkgs, err = parser.ParseDir(fset, opts.Dir, nil, mode)
+		p.dir = opts.Dir
+		p.pkgs, err = parseDir(fset, opts.Dir, opts.Build, mode)
 		if err != nil {
-			return nil, err
+			return nil, toParseError(err)
 		}
 	case opts.Src != nil:
+		p.src, p.filename = opts.Src, "src.go"
 		p.file, err = parser.ParseFile(fset, "src.go", opts.Src, mode)
 		if err != nil {
-			return nil, err
+			return nil, toParseError(err)
 		}
 	default:
-		return nil, errors.New("file, src or dir is not specified")
+		return nil, ErrNoSource
 	}
 
 	return p, nil
 }
+
+// FileSet returns the file set of the parsed source
+func (p *Parser) FileSet() *token.FileSet {
+	p.syntax()
+	return p.fset
+}
+
+// File returns the parsed file. It's nil if a directory was parsed.
+func (p *Parser) File() *ast.File { return p.syntax() }
+
+// pos returns the token.Pos of the given byte offset in the parsed file. It
+// returns an error if the parser doesn't contain a single file or if the
+// offset is outside of the file.
+func (p *Parser) pos(offset int) (token.Pos, error) {
+	if p.file == nil {
+		return token.NoPos, ErrFileRequired
+	}
+
+	// syntax replaces the file set of a stale file, it has to be called
+	// before the file set is used
+	file := p.syntax()
+	tf := p.fset.File(file.Pos())
+	if offset < 0 || offset > tf.Size() {
+		return token.NoPos, ErrOffsetOutOfRange
+	}
+
+	return tf.Pos(offset), nil
+}
@@ -21,6 +21,39 @@ type Comment struct {
 	StartCol  int `json:"startCol" vim:"startCol"`
 	EndLine   int `json:"endLine" vim:"endLine"`
 	EndCol    int `json:"endCol" vim:"endCol"`
+
+	// Kind is either "line" for // comments or "block" for /* */ comments
+	Kind string `json:"kind" vim:"kind"`
+
+	// Role is one of "doc", "directive" or "comment". A comment group which
+	// only consists of directives, i.e: //go:generate, is a directive even if
+	// it's attached to a declaration.
+	Role string `json:"role" vim:"role"`
+
+	// Inner is the text of the comment without the comment markers and the
+	// surrounding whitespace, to be used by "inner comment" text objects
+	Inner Range `json:"inner" vim:"inner"`
+
+	// Outer is the comment with its indentation and the trailing newline and
+	// blank line, to be used by "a comment" text objects. For comments
+	// following code on the same line it's the comment with the whitespace
+	// before it.
+	Outer Range `json:"outer" vim:"outer"`
+
+	// Decl is the declaration documented by the comment, if any
+	Decl *Decl `json:"decl,omitempty" vim:"decl,omitempty"`
+
+	// Directives are the directives of the comment group, including the
+	// ones following the prose of a doc comment
+	Directives []Directive `json:"directives,omitempty" vim:"directives,omitempty"`
+}
+
+// Range is a range of a file, the end column is exclusive
+type Range struct {
+	StartLine int `json:"startLine" vim:"startLine"`
+	StartCol  int `json:"startCol" vim:"startCol"`
+	EndLine   int `json:"endLine" vim:"endLine"`
+	EndCol    int `json:"endCol" vim:"endCol"`
 }
 
 // Result is the common result of any motion query.
@@ -28,21 +61,77 @@ type Comment struct {
 type Result struct {
 	Mode string `json:"mode" vim:"mode"`
 
-	Comment Comment `json:"comment,omitempty" vim:"comment,omitempty"`
-	Decls   []Decl  `json:"decls,omitempty" vim:"decls,omitempty"`
-	Func    *Func   `json:"func,omitempty" vim:"fn,omitempty"`
+	// Version is the SchemaVersion of the result
+	Version int `json:"version" vim:"version"`
+
+	Comment *Comment `json:"comment,omitempty" vim:"comment,omitempty"`
+	Decls   []Decl   `json:"decls,omitempty" vim:"decls,omitempty"`
+	Func    *Func    `json:"func,omitempty" vim:"fn,omitempty"`
+	Imports *Imports `json:"imports,omitempty" vim:"imports,omitempty"`
+	Context *Context `json:"context,omitempty" vim:"context,omitempty"`
+	Symbols *Symbols `json:"symbols,omitempty" vim:"symbols,omitempty"`
+	Folds   []Fold   `json:"folds,omitempty" vim:"folds,omitempty"`
+	Todos   []Todo   `json:"todos,omitempty" vim:"todos,omitempty"`
+
+	Directives []Directive `json:"directives,omitempty" vim:"directives,omitempty"`
+
+	// Edit is the edit of the "reflowdoc" mode
+	Edit *TextEdit `json:"edit,omitempty" vim:"edit,omitempty"`
+
+	DocStubs []DocStub `json:"docstubs,omitempty" vim:"docstubs,omitempty"`
+
+	// Funcs are the functions of the "metrics" mode
+	Funcs Funcs `json:"funcs,omitempty" vim:"funcs,omitempty"`
+
+	// Exits are the exit points of the "returns" mode, or the single exit
+	// point of the "nextreturn" and "prevreturn" modes
+	Exits []Exit `json:"exits,omitempty" vim:"exits,omitempty"`
 }
 
 // Query specifies a single query to the parser
 type Query struct {
-	Mode     string
-	Offset   int
-	Shift    int
-	Includes []string
+	Mode     string   `json:"mode" vim:"mode"`
+	Offset   int      `json:"offset" vim:"offset"`
+	Shift    int      `json:"shift" vim:"shift"`
+	Includes []string `json:"includes" vim:"includes"`
+
+	// Markers are the markers of the "todos" mode
+	Markers []string `json:"markers,omitempty" vim:"markers,omitempty"`
+
+	// Width is the width of the "reflowdoc" mode, see Parser.ReflowDoc
+	Width int `json:"width,omitempty" vim:"width,omitempty"`
+
+	// Metrics includes the metrics of the functions of the "enclosing",
+	// "next" and "prev" modes
+	Metrics bool `json:"metrics,omitempty" vim:"metrics,omitempty"`
+
+	// Symbol, Page and Limit are the query and the page of the "symbols"
+	// mode, see SymbolOptions
+	Symbol string `json:"symbol,omitempty" vim:"symbol,omitempty"`
+	Page   int    `json:"page,omitempty" vim:"page,omitempty"`
+	Limit  int    `json:"limit,omitempty" vim:"limit,omitempty"`
+
+	// Skip are the patterns of the paths skipped by the "symbols" and the
+	// recursive "todos" modes
+	Skip []string `json:"skip,omitempty" vim:"skip,omitempty"`
+
+	// Recursive walks the parsed directory recursively in the "todos" mode,
+	// instead of only using the parsed files
+	Recursive bool `json:"recursive,omitempty" vim:"recursive,omitempty"`
 }
 
 // Run runs the given query and returns the result
 func (p *Parser) Run(query *Query) (*Result, error) {
+	res, err := p.run(query)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Version = SchemaVersion
+	return res, nil
+}
+
+func (p *Parser) run(query *Query) (*Result, error) {
 	if query == nil {
 		return nil, errors.New("query is nil")
 	}
@@ -52,14 +141,14 @@ func (p *Parser) Run(query *Query) (*Result, error) {
 		var fn *Func
 		var err error
 
-		funcs := p.Funcs()
+		index := p.FuncIndex()
 		switch query.Mode {
 		case "enclosing":
-			fn, err = funcs.EnclosingFunc(query.Offset)
+			fn, err = index.EnclosingFunc(query.Offset)
 		case "next":
-			fn, err = funcs.Declarations().NextFuncShift(query.Offset, query.Shift)
+			fn, err = index.NextFuncShift(query.Offset, query.Shift)
 		case "prev":
-			fn, err = funcs.Declarations().PrevFuncShift(query.Offset, query.Shift)
+			fn, err = index.PrevFuncShift(query.Offset, query.Shift)
 		}
 
 		// do no return, instead pass it to the editor so it can parse it
@@ -67,6 +156,10 @@ func (p *Parser) Run(query *Query) (*Result, error) {
 			return nil, err
 		}
 
+		if query.Metrics {
+			fn = p.withMetrics(fn)
+		}
+
 		return &Result{
 			Mode: query.Mode,
 			Func: fn,
@@ -109,30 +202,157 @@ func (p *Parser) Run(query *Query) (*Result, error) {
 			Decls: decls,
 		}, nil
 	case "comment":
-		var comment *Comment
-		for _, c := range p.file.Comments {
-			if int(c.Pos()) <= query.Offset+1 && int(c.End()) >= query.Offset {
-				start := p.fset.Position(c.Pos())
-				end := p.fset.Position(c.End())
-				comment = &Comment{
-					StartLine: start.Line,
-					StartCol:  start.Column,
-					EndLine:   end.Line,
-					EndCol:    end.Column,
-				}
-				break
-			}
+		comment, err := p.Comment(query.Offset)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Result{
+			Comment: comment,
+			Mode:    query.Mode,
+		}, nil
+	case "imports":
+		imports, err := p.Imports()
+		if err != nil {
+			return nil, err
 		}
 
-		if comment == nil {
-			return nil, errors.New("no comment block at cursor position")
+		return &Result{
+			Mode:    query.Mode,
+			Imports: imports,
+		}, nil
+	case "context":
+		ctx, err := p.Context(query.Offset)
+		if err != nil {
+			return nil, err
 		}
 
 		return &Result{
-			Comment: *comment,
 			Mode:    query.Mode,
+			Context: ctx,
+		}, nil
+	case "folds":
+		folds, err := p.Folds()
+		if err != nil {
+			return nil, err
+		}
+
+		return &Result{
+			Mode:  query.Mode,
+			Folds: folds,
+		}, nil
+	case "symbols":
+		if p.dir == "" {
+			return nil, fmt.Errorf("symbols %w", ErrDirRequired)
+		}
+
+		symbols, err := FindSymbols(&SymbolOptions{
+			Root:  p.dir,
+			Query: query.Symbol,
+			Skip:  query.Skip,
+			Page:  query.Page,
+			Limit: query.Limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &Result{
+			Mode:    query.Mode,
+			Symbols: symbols,
+		}, nil
+	case "todos":
+		var todos []Todo
+		var err error
+		switch {
+		case !query.Recursive:
+			todos, err = p.Todos(query.Markers)
+		case p.dir == "":
+			err = fmt.Errorf("recursive todos %w", ErrDirRequired)
+		default:
+			todos, err = FindTodos(&TodoOptions{
+				Root:    p.dir,
+				Skip:    query.Skip,
+				Markers: query.Markers,
+			})
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &Result{
+			Mode:  query.Mode,
+			Todos: todos,
+		}, nil
+	case "directives":
+		directives, err := p.Directives()
+		if err != nil {
+			return nil, err
+		}
+
+		return &Result{
+			Mode:       query.Mode,
+			Directives: directives,
+		}, nil
+	case "reflowdoc":
+		edit, err := p.ReflowDoc(query.Offset, query.Width)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Result{
+			Mode: query.Mode,
+			Edit: edit,
+		}, nil
+	case "docstub":
+		stub, err := p.DocStub(query.Offset)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Result{
+			Mode:     query.Mode,
+			DocStubs: []DocStub{*stub},
+		}, nil
+	case "docstubs":
+		return &Result{
+			Mode:     query.Mode,
+			DocStubs: p.DocStubs(),
+		}, nil
+	case "metrics":
+		return &Result{
+			Mode:  query.Mode,
+			Funcs: p.RankedFuncs(),
+		}, nil
+	case "returns":
+		exits, err := p.Exits(query.Offset)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Result{
+			Mode:  query.Mode,
+			Exits: exits,
+		}, nil
+	case "nextreturn", "prevreturn":
+		var exit *Exit
+		var err error
+		if query.Mode == "nextreturn" {
+			exit, err = p.NextExit(query.Offset, query.Shift)
+		} else {
+			exit, err = p.PrevExit(query.Offset, query.Shift)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &Result{
+			Mode:  query.Mode,
+			Exits: []Exit{*exit},
 		}, nil
 	default:
-		return nil, fmt.Errorf("wrong mode %q passed", query.Mode)
+		return nil, fmt.Errorf("%w %q", ErrUnknownMode, query.Mode)
 	}
 }
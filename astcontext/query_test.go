@@ -2,7 +2,6 @@ package astcontext
 
 import (
 	"fmt"
-	"reflect"
 	"strings"
 	"testing"
 )
@@ -26,14 +25,14 @@ bar */
 
 	cases := []struct {
 		offset  int
-		want    Comment
+		want    Range
 		wantErr string
 	}{
-		{4, Comment{}, "no comment block"},
-		{9000, Comment{}, "no comment block"},
-		{18, Comment{3, 1, 3, 9}, ""},
-		{24, Comment{5, 1, 6, 7}, ""},
-		{39, Comment{8, 1, 9, 6}, ""},
+		{4, Range{}, "no comment block"},
+		{9000, Range{}, "no comment block"},
+		{18, Range{3, 1, 3, 9}, ""},
+		{24, Range{5, 1, 6, 7}, ""},
+		{39, Range{8, 1, 9, 6}, ""},
 	}
 
 	for _, tc := range cases {
@@ -47,13 +46,50 @@ bar */
 				return
 			}
 
-			if !reflect.DeepEqual(out.Comment, tc.want) {
-				t.Fatalf("wrong output:\nwant: %v\ngot:  %v", tc.want, out.Comment)
+			c := out.Comment
+			got := Range{c.StartLine, c.StartCol, c.EndLine, c.EndCol}
+			if got != tc.want {
+				t.Fatalf("wrong output:\nwant: %v\ngot:  %v", tc.want, got)
 			}
 		})
 	}
 }
 
+func TestRun_SameParser(t *testing.T) {
+	var src = `package main
+
+func foo() {}
+
+func bar() {}
+
+func qux() {}
+`
+	opts := &ParserOptions{Src: []byte(src)}
+	parser, err := NewParser(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// running the same query multiple times against a single parser should
+	// always return the same function
+	for i := 0; i < 3; i++ {
+		out, err := parser.Run(&Query{Mode: "prev", Offset: 40})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if out.Func.Signature.Name != "bar" {
+			t.Fatalf("run %d: wrong function, want: bar, got: %s", i, out.Func.Signature.Name)
+		}
+	}
+
+	funcs := parser.Funcs()
+	funcs.Reserve()
+	if name := parser.Funcs()[0].Signature.Name; name != "foo" {
+		t.Errorf("modifying Funcs() should not change the parser, got: %s", name)
+	}
+}
+
 // errorContains checks if the error message in out contains the text in
 // want.
 //
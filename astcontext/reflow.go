@@ -0,0 +1,313 @@
+package astcontext
+
+import (
+	"go/ast"
+	"go/doc/comment"
+	"go/token"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultReflowWidth is the width of the "reflowdoc" mode if no width is
+// specified
+const DefaultReflowWidth = 80
+
+// ReflowDoc returns an edit which wraps the doc comment of the function or
+// type declaration at the given offset to the given width. The width
+// includes the indentation and the comment markers, a tab counts as a single
+// column. If width is zero, DefaultReflowWidth is used.
+//
+// The comment is formatted the same as gofmt does and follows the doc comment
+// syntax of go/doc/comment: paragraphs and list items are wrapped, headings,
+// code blocks and link definitions are kept as they are. Directives are
+// moved to the end of the comment. Block comments are replaced with line
+// comments. The source has to be parsed with comments.
+func (p *Parser) ReflowDoc(offset, width int) (*TextEdit, error) {
+	pos, err := p.pos(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	if width <= 0 {
+		width = DefaultReflowWidth
+	}
+
+	file := p.syntax()
+	group := docAt(file, pos)
+	if group == nil {
+		return nil, ErrNoDocComment
+	}
+
+	// the directives are kept as they are, the text of the other comments is
+	// wrapped. CommentGroup.Text only drops the directives of the "//x:y"
+	// form, but not i.e: //nolint.
+	prose := &ast.CommentGroup{}
+	var directives []*ast.Comment
+	for _, c := range group.List {
+		if isDirective(c.Text) {
+			directives = append(directives, c)
+			continue
+		}
+		prose.List = append(prose.List, c)
+	}
+
+	if len(prose.List) == 0 {
+		// only directives, there is nothing to wrap
+		return nil, ErrNoDocComment
+	}
+
+	text := prose.Text()
+	if strings.HasPrefix(prose.List[0].Text, "/*") {
+		text = unindentBlock(text)
+	}
+
+	if strings.TrimSpace(text) == "" {
+		return nil, ErrNoDocComment
+	}
+
+	tf := p.fset.File(file.Pos())
+	start, end := tf.Offset(group.Pos()), tf.Offset(group.End())
+	indent := p.indentation(tf, start)
+
+	var parser comment.Parser
+	doc := parser.Parse(text)
+	wrapDoc(doc, width-utf8.RuneCountInString(indent)-len("// "))
+
+	var printer comment.Printer
+	out := strings.TrimSuffix(string(printer.Comment(doc)), "\n")
+
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case line == "":
+			lines = append(lines, "//")
+		case line[0] == '\t':
+			// code blocks
+			lines = append(lines, "//"+line)
+		default:
+			lines = append(lines, "// "+line)
+		}
+	}
+
+	if len(directives) > 0 {
+		lines = append(lines, "//")
+	}
+	for _, c := range directives {
+		lines = append(lines, c.Text)
+	}
+
+	return &TextEdit{
+		Start: start,
+		End:   end,
+		Text:  strings.Join(lines, "\n"+indent),
+	}, nil
+}
+
+// docAt returns the doc comment of the top level function or type
+// declaration at the given position, including its doc comment. It returns
+// nil if there is no such declaration or it's not documented.
+func docAt(file *ast.File, pos token.Pos) *ast.CommentGroup {
+	within := func(doc *ast.CommentGroup, node ast.Node) bool {
+		return doc != nil && doc.Pos() <= pos && pos <= node.End()
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if within(d.Doc, d) {
+				return d.Doc
+			}
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+
+			if !d.Lparen.IsValid() {
+				if within(d.Doc, d) {
+					return d.Doc
+				}
+				continue
+			}
+
+			for _, spec := range d.Specs {
+				if s := spec.(*ast.TypeSpec); within(s.Doc, s) {
+					return s.Doc
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// unindentBlock removes the indentation of the text of a block comment, so
+// aligned lines aren't parsed as code blocks. The lines after the first one
+// are unindented by their common indentation.
+func unindentBlock(text string) string {
+	lines := strings.Split(text, "\n")
+	lines[0] = strings.TrimLeft(lines[0], " \t")
+
+	indent := ""
+	found := false
+	for _, line := range lines[1:] {
+		if isBlank([]byte(line)) {
+			continue
+		}
+
+		lineIndent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			indent, found = lineIndent, true
+			continue
+		}
+
+		for !strings.HasPrefix(lineIndent, indent) {
+			indent = indent[:len(indent)-1]
+		}
+	}
+
+	for i := 1; i < len(lines); i++ {
+		lines[i] = strings.TrimPrefix(lines[i], indent)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indentation returns the whitespace before the given offset on its line
+func (p *Parser) indentation(tf *token.File, offset int) string {
+	src := p.source(tf)
+	if src == nil {
+		return ""
+	}
+
+	start := offset
+	for start > 0 && (src[start-1] == ' ' || src[start-1] == '\t') {
+		start--
+	}
+	return string(src[start:offset])
+}
+
+// wrapDoc wraps the paragraphs and list items of the doc to the given width.
+// The width excludes the comment markers.
+func wrapDoc(doc *comment.Doc, width int) {
+	// the indentation of list items, see comment.Printer
+	const itemIndent = len("    ")
+
+	for _, block := range doc.Content {
+		switch x := block.(type) {
+		case *comment.Paragraph:
+			wrapText(x.Text, width, width)
+		case *comment.List:
+			for _, item := range x.Items {
+				// "  - " or " 1. "
+				marker := itemIndent
+				if item.Number != "" {
+					marker = len(" " + item.Number + ". ")
+				}
+
+				for i, blk := range item.Content {
+					first := width - itemIndent
+					if i == 0 {
+						first = width - marker
+					}
+					wrapText(blk.(*comment.Paragraph).Text, first, width-itemIndent)
+				}
+			}
+		}
+	}
+}
+
+// wrapAtom is either a run of whitespace or a part of a word of a text. The
+// brackets of links are parts of words which don't belong to a segment.
+type wrapAtom struct {
+	seg   int
+	text  string
+	space bool
+}
+
+// wrapText wraps the given text in place. The first line is wrapped to first,
+// the other lines to rest. Words are never split, the text of links is
+// wrapped like plain text.
+func wrapText(text []comment.Text, first, rest int) {
+	var atoms []wrapAtom
+	var setters []func(string)
+
+	addSeg := func(s string, set func(string)) {
+		seg := len(setters)
+		setters = append(setters, set)
+
+		for s != "" {
+			space := isSpace(s[0])
+			n := 0
+			for n < len(s) && isSpace(s[n]) == space {
+				n++
+			}
+			atoms = append(atoms, wrapAtom{seg: seg, text: s[:n], space: space})
+			s = s[n:]
+		}
+	}
+	bracket := func(b string) {
+		atoms = append(atoms, wrapAtom{seg: -1, text: b})
+	}
+
+	var walk func(x []comment.Text)
+	walk = func(x []comment.Text) {
+		for i := range x {
+			i := i
+			switch t := x[i].(type) {
+			case comment.Plain:
+				addSeg(string(t), func(s string) { x[i] = comment.Plain(s) })
+			case comment.Italic:
+				addSeg(string(t), func(s string) { x[i] = comment.Italic(s) })
+			case *comment.Link:
+				if !t.Auto {
+					bracket("[")
+				}
+				walk(t.Text)
+				if !t.Auto {
+					bracket("]")
+				}
+			case *comment.DocLink:
+				bracket("[")
+				walk(t.Text)
+				bracket("]")
+			}
+		}
+	}
+	walk(text)
+
+	col, width := 0, first
+	for k := range atoms {
+		a := &atoms[k]
+		if !a.space {
+			col += utf8.RuneCountInString(a.text)
+			continue
+		}
+
+		// the next word can span multiple atoms, i.e: "[Foo]."
+		next, j := 0, k+1
+		for ; j < len(atoms) && !atoms[j].space; j++ {
+			next += utf8.RuneCountInString(atoms[j].text)
+		}
+
+		switch {
+		case col == 0 || next == 0:
+			// leading or trailing whitespace
+			a.text = ""
+		case col+1+next > width:
+			a.text = "\n"
+			col, width = 0, rest
+		default:
+			a.text = " "
+			col++
+		}
+	}
+
+	segs := make([]strings.Builder, len(setters))
+	for _, a := range atoms {
+		if a.seg >= 0 {
+			segs[a.seg].WriteString(a.text)
+		}
+	}
+	for i, set := range setters {
+		set(segs[i].String())
+	}
+}
@@ -0,0 +1,151 @@
+package astcontext
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const reflowSrc = `package main
+
+// Foo does a lot of things which need a long description, so that the line is too long.
+// It wraps [strings.Builder] and
+// the [Go spec] links.
+//
+// # Usage
+//
+// Call it like:
+//
+//	foo := Foo()
+//	    if foo {
+//	}
+//
+// Items:
+//   - a list item which is long enough to be wrapped
+//   - second
+//
+// [Go spec]: https://go.dev/ref/spec
+//
+//go:noinline
+func Foo() bool { return true }
+
+type (
+	/* T is a type with a block comment which is wrapped
+	   as well. */
+	T int
+)
+
+func Undocumented() {}
+`
+
+func TestParser_ReflowDoc(t *testing.T) {
+	parser, err := NewParser(&ParserOptions{Src: []byte(reflowSrc), Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		offset int
+		width  int
+		want   string
+	}{
+		{
+			offset: strings.Index(reflowSrc, "return true"),
+			width:  40,
+			want: `// Foo does a lot of things which need a
+// long description, so that the line is
+// too long. It wraps [strings.Builder]
+// and the [Go spec] links.
+//
+// # Usage
+//
+// Call it like:
+//
+//	foo := Foo()
+//	    if foo {
+//	}
+//
+// Items:
+//   - a list item which is long enough
+//     to be wrapped
+//   - second
+//
+// [Go spec]: https://go.dev/ref/spec
+//
+//go:noinline`,
+		},
+		{
+			offset: strings.Index(reflowSrc, "T int"),
+			width:  30,
+			want: "// T is a type with a block\n" +
+				"\t// comment which is wrapped\n" +
+				"\t// as well.",
+		},
+	}
+
+	for _, tc := range cases {
+		edit, err := parser.ReflowDoc(tc.offset, tc.width)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if edit.Text != tc.want {
+			t.Errorf("offset %d: wrong text\nwant:\n%s\ngot:\n%s", tc.offset, tc.want, edit.Text)
+		}
+
+		// reflowing the result again doesn't change it
+		src := reflowSrc[:edit.Start] + edit.Text + reflowSrc[edit.End:]
+		p, err := NewParser(&ParserOptions{Src: []byte(src), Comments: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		again, err := p.ReflowDoc(edit.Start, tc.width)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.Text != edit.Text {
+			t.Errorf("offset %d: reflow is not stable\nwant:\n%s\ngot:\n%s", tc.offset, edit.Text, again.Text)
+		}
+	}
+
+	if _, err := parser.ReflowDoc(strings.Index(reflowSrc, "Undocumented"), 0); !errors.Is(err, ErrNoDocComment) {
+		t.Errorf("want no doc comment error, got: %v", err)
+	}
+}
+
+func TestParser_ReflowDocWidth(t *testing.T) {
+	src := "package main\n\n// Foo\n// does\n// things.\nfunc Foo() {}\n"
+	parser, err := NewParser(&ParserOptions{Src: []byte(src), Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := parser.Run(&Query{Mode: "reflowdoc", Offset: strings.Index(src, "func")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := TextEdit{Start: 14, End: 39, Text: "// Foo does things."}
+	if *res.Edit != want {
+		t.Errorf("wrong edit\nwant: %+v\ngot:  %+v", want, *res.Edit)
+	}
+}
+
+func TestParser_ReflowDocDirective(t *testing.T) {
+	src := "package main\n\n// Foo does x and some more words here.\n//nolint\nfunc Foo() {}\n"
+	parser, err := NewParser(&ParserOptions{Src: []byte(src), Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edit, err := parser.ReflowDoc(strings.Index(src, "func"), 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "// Foo does x and\n// some more words\n// here.\n//\n//nolint"
+	if edit.Text != want {
+		t.Errorf("wrong text\nwant:\n%s\ngot:\n%s", want, edit.Text)
+	}
+}
@@ -0,0 +1,139 @@
+package astcontext
+
+import (
+	"reflect"
+	"strings"
+)
+
+// SchemaVersion is the version of the output schema. It's increased whenever
+// the shape of Result or ErrorResult changes, so editors can detect the
+// supported features instead of depending on a specific motion release.
+const SchemaVersion = 1
+
+// Schema returns a JSON Schema (draft 2020-12) describing the output of
+// motion: a Result, an ErrorResult or an array of them for a batch of queries.
+// The property names are read from the given struct tag, i.e: "json" or
+// "vim", falling back to the "json" tag if a field doesn't have it.
+func Schema(tag string) map[string]interface{} {
+	g := &schemaGen{tag: tag, defs: map[string]interface{}{}}
+
+	result := g.schema(reflect.TypeOf(Result{}))
+	errResult := g.schema(reflect.TypeOf(ErrorResult{}))
+
+	single := map[string]interface{}{"oneOf": []interface{}{result, errResult}}
+	return map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "motion output",
+		"version": SchemaVersion,
+		"oneOf": []interface{}{
+			single,
+			map[string]interface{}{"type": "array", "items": single},
+		},
+		"$defs": g.defs,
+	}
+}
+
+// schemaGen generates the JSON Schema of Go types. Named struct types are
+// added to defs and referenced.
+type schemaGen struct {
+	tag  string
+	defs map[string]interface{}
+}
+
+func (g *schemaGen) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = nil // break cycles
+			g.defs[t.Name()] = g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	}
+
+	// interfaces can be anything
+	return map[string]interface{}{}
+}
+
+// object returns the schema of the given struct type
+func (g *schemaGen) object(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	required := []string{}
+	g.fields(t, props, &required)
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// fields adds the properties of the fields of t, flattening embedded structs
+// the same way as encoding/json does.
+func (g *schemaGen) fields(t reflect.Type, props map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup(g.tag)
+		if !ok {
+			tag = sf.Tag.Get("json")
+		}
+
+		if !sf.IsExported() || tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			g.fields(sf.Type, props, required)
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		props[name] = g.schema(sf.Type)
+
+		// the version is known, pin it so a validator catches a mismatch
+		if sf.Name == "Version" && (t == reflect.TypeOf(Result{}) || t == reflect.TypeOf(ErrorResult{})) {
+			props[name] = map[string]interface{}{"type": "integer", "const": SchemaVersion}
+		}
+
+		// omitempty has no effect on structs
+		omitEmpty := strings.Contains(","+opts+",", ",omitempty,")
+		if !omitEmpty || sf.Type.Kind() == reflect.Struct {
+			*required = append(*required, name)
+		}
+
+		// nil values of required fields are encoded as null
+		switch sf.Type.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map:
+			if !omitEmpty {
+				props[name] = map[string]interface{}{
+					"anyOf": []interface{}{props[name], map[string]interface{}{"type": "null"}},
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package astcontext
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSchema_Tags(t *testing.T) {
+	cases := []struct {
+		tag  string
+		want string
+	}{
+		{"json", "func"},
+		{"vim", "fn"},
+		{"lua", "func"},
+	}
+
+	for _, tc := range cases {
+		defs := Schema(tc.tag)["$defs"].(map[string]interface{})
+		props := defs["Result"].(map[string]interface{})["properties"].(map[string]interface{})
+
+		if _, ok := props[tc.want]; !ok {
+			t.Errorf("%s: missing %q property", tc.tag, tc.want)
+		}
+
+		want := map[string]interface{}{"type": "integer", "const": SchemaVersion}
+		if !reflect.DeepEqual(props["version"], want) {
+			t.Errorf("%s: wrong version property: %v", tc.tag, props["version"])
+		}
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	var src = `package main
+
+import "fmt"
+
+// Foo is a function
+func Foo() {
+	fmt.Println("foo")
+}
+
+type Bar struct {
+	Name string
+}
+`
+	parser, err := NewParser(&ParserOptions{Src: []byte(src), Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a forward declaration has no braces
+	decl, err := NewParser(&ParserOptions{Src: []byte("package main\n\nfunc foo()\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next, err := decl.Run(&Query{Mode: "next", Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs := []interface{}{next}
+	for _, query := range []*Query{
+		{Mode: "enclosing", Offset: 60},
+		{Mode: "next", Offset: 1},
+		{Mode: "decls", Includes: []string{"func", "type"}},
+		{Mode: "comment", Offset: 30},
+		{Mode: "imports"},
+		{Mode: "context", Offset: 60},
+		{Mode: "folds"},
+	} {
+		res, err := parser.Run(query)
+		if err != nil {
+			t.Fatalf("%s: %s", query.Mode, err)
+		}
+		outputs = append(outputs, res)
+	}
+
+	symbols, err := FindSymbols(&SymbolOptions{Root: ".", Query: "NoSuchSymbol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs = append(outputs,
+		NewErrorResult(ErrNoFunc),
+		&Result{Mode: "symbols", Version: SchemaVersion, Symbols: symbols},
+	)
+
+	_, err = NewParser(&ParserOptions{Src: []byte("package main\nfunc (")})
+	outputs = append(outputs, NewErrorResult(err))
+
+	// a batch of queries
+	outputs = append(outputs, outputs[:4])
+
+	schema := Schema("json")
+	defs := schema["$defs"].(map[string]interface{})
+	for _, out := range outputs {
+		b, err := json.Marshal(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := validate(schema, defs, v); err != nil {
+			t.Errorf("%s\ndoesn't match the schema: %s", b, err)
+		}
+	}
+
+	// results of a newer schema version are rejected
+	b, _ := json.Marshal(&Result{Mode: "folds", Version: SchemaVersion + 1})
+	var v interface{}
+	json.Unmarshal(b, &v)
+	if err := validate(schema, defs, v); err == nil {
+		t.Error("a result with a different version should not match the schema")
+	}
+}
+
+// validate is a minimal JSON Schema validator, supporting only the keywords
+// generated by Schema.
+func validate(schema map[string]interface{}, defs map[string]interface{}, v interface{}) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		return validate(defs[ref[len("#/$defs/"):]].(map[string]interface{}), defs, v)
+	}
+
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		matches := 0
+		var errs []error
+		for _, s := range oneOf {
+			err := validate(s.(map[string]interface{}), defs, v)
+			if err == nil {
+				matches++
+			}
+			errs = append(errs, err)
+		}
+		if matches != 1 {
+			return fmt.Errorf("%d oneOf schemas match: %v", matches, errs)
+		}
+		return nil
+	}
+
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		var errs []error
+		for _, s := range anyOf {
+			err := validate(s.(map[string]interface{}), defs, v)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err)
+		}
+		return fmt.Errorf("no anyOf schema matches: %v", errs)
+	}
+
+	if c, ok := schema["const"]; ok && fmt.Sprint(c) != fmt.Sprint(v) {
+		return fmt.Errorf("want const %v, got %v", c, v)
+	}
+
+	switch schema["type"] {
+	case "null":
+		if v != nil {
+			return fmt.Errorf("want null, got %T", v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("want boolean, got %T", v)
+		}
+	case "integer":
+		if f, ok := v.(float64); !ok || f != float64(int64(f)) {
+			return fmt.Errorf("want integer, got %v", v)
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("want string, got %T", v)
+		}
+	case "array":
+		list, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("want array, got %T", v)
+		}
+		for _, item := range list {
+			if err := validate(schema["items"].(map[string]interface{}), defs, item); err != nil {
+				return err
+			}
+		}
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("want object, got %T", v)
+		}
+
+		for _, name := range schema["required"].([]string) {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("missing required property %q", name)
+			}
+		}
+
+		props := schema["properties"].(map[string]interface{})
+		for name, value := range obj {
+			prop, ok := props[name]
+			if !ok {
+				return fmt.Errorf("unknown property %q", name)
+			}
+			if err := validate(prop.(map[string]interface{}), defs, value); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	default:
+		return errors.New("unknown type")
+	}
+
+	return nil
+}
@@ -0,0 +1,325 @@
+package astcontext
+
+import (
+	"bufio"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultSymbolsLimit is the number of symbols returned per page if no limit
+// is specified
+const DefaultSymbolsLimit = 50
+
+// SymbolOptions defines the options of a workspace wide symbol search
+type SymbolOptions struct {
+	// Root is the directory to be walked recursively
+	Root string
+
+	// Query is matched against the declaration identifiers. An empty query
+	// matches all declarations.
+	Query string
+
+	// Skip defines additional .gitignore like patterns of files and
+	// directories to be skipped. Patterns without a slash are matched against
+	// the base name, others against the slash separated path relative to
+	// Root. The patterns of the .gitignore file in Root are always included.
+	Skip []string
+
+	// Page is the zero based page index and Limit the number of symbols per
+	// page. If Limit is zero, DefaultSymbolsLimit is used.
+	Page  int
+	Limit int
+}
+
+// Symbols specifies the result of the "symbols" mode
+type Symbols struct {
+	// Total is the number of all matching declarations
+	Total int `json:"total" vim:"total"`
+	Page  int `json:"page" vim:"page"`
+
+	// Decls are the matching declarations of the page, sorted by rank
+	Decls []Decl `json:"decls" vim:"decls"`
+}
+
+// FindSymbols walks the given root recursively and returns the top level
+// function and type declarations matching the query. Directories named
+// vendor and testdata, hidden directories and directories starting with an
+// underscore are skipped, the same as the go tool does.
+func FindSymbols(opts *SymbolOptions) (*Symbols, error) {
+	if opts == nil || opts.Root == "" {
+		return nil, errors.New("root directory is not specified")
+	}
+
+	if opts.Page < 0 || opts.Limit < 0 {
+		return nil, errors.New("page and limit can't be negative")
+	}
+
+	files, err := walkGoFiles(opts.Root, opts.Skip)
+	if err != nil {
+		return nil, err
+	}
+
+	type match struct {
+		decl  Decl
+		score int
+	}
+
+	var matches []match
+	for _, decl := range parseDecls(files) {
+		score := symbolScore(opts.Query, decl.Ident)
+		if score <= 0 {
+			continue
+		}
+		matches = append(matches, match{decl: decl, score: score})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		switch {
+		case a.score != b.score:
+			return a.score > b.score
+		case a.decl.Ident != b.decl.Ident:
+			return a.decl.Ident < b.decl.Ident
+		case a.decl.Filename != b.decl.Filename:
+			return a.decl.Filename < b.decl.Filename
+		default:
+			return a.decl.Line < b.decl.Line
+		}
+	})
+
+	limit := opts.Limit
+	if limit == 0 {
+		limit = DefaultSymbolsLimit
+	}
+
+	symbols := &Symbols{
+		Total: len(matches),
+		Page:  opts.Page,
+		Decls: []Decl{},
+	}
+
+	for i := opts.Page * limit; i < len(matches) && i < (opts.Page+1)*limit; i++ {
+		symbols.Decls = append(symbols.Decls, matches[i].decl)
+	}
+
+	return symbols, nil
+}
+
+// walkGoFiles returns all Go files under root, excluding the skipped files
+// and directories.
+func walkGoFiles(root string, skip []string) ([]string, error) {
+	patterns := append(readIgnoreFile(filepath.Join(root, ".gitignore")), skip...)
+
+	isSkipped := func(rel string) bool {
+		for _, pattern := range patterns {
+			if strings.Contains(pattern, "/") {
+				if ok, _ := path.Match(strings.TrimPrefix(pattern, "/"), rel); ok {
+					return true
+				}
+				continue
+			}
+
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if p == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			name := d.Name()
+			if name == "vendor" || name == "testdata" ||
+				strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+				isSkipped(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !strings.HasSuffix(p, ".go") || isSkipped(rel) {
+			return nil
+		}
+
+		files = append(files, p)
+		return nil
+	})
+
+	return files, err
+}
+
+// readIgnoreFile returns the patterns of the given .gitignore file. Comments
+// and negated patterns are ignored. A missing file returns no patterns.
+func readIgnoreFile(filename string) []string {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+
+		patterns = append(patterns, strings.TrimSuffix(line, "/"))
+	}
+
+	return patterns
+}
+
+// parseDecls parses the given files concurrently and returns their top level
+// function and type declarations. Files which can't be read are skipped,
+// files with syntax errors contribute the declarations that could be parsed.
+func parseDecls(files []string) []Decl {
+	fset := token.NewFileSet()
+	results := make([][]Decl, len(files))
+
+	forEach(len(files), func(i int) {
+		file, _ := parser.ParseFile(fset, files[i], nil, parser.SkipObjectResolution)
+		if file != nil {
+			results[i] = fileDecls(fset, file)
+		}
+	})
+
+	var decls []Decl
+	for _, d := range results {
+		decls = append(decls, d...)
+	}
+	return decls
+}
+
+// fileDecls returns the top level function and type declarations of the
+// given file.
+func fileDecls(fset *token.FileSet, file *ast.File) []Decl {
+	var decls []Decl
+	for _, d := range file.Decls {
+		switch x := d.(type) {
+		case *ast.FuncDecl:
+			pos := fset.Position(x.Type.Func)
+			decls = append(decls, Decl{
+				Keyword:  "func",
+				Ident:    x.Name.Name,
+				Full:     NewFuncSignature(x).Full,
+				Filename: pos.Filename,
+				Line:     pos.Line,
+				Col:      pos.Column,
+			})
+		case *ast.GenDecl:
+			if x.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range x.Specs {
+				ts := spec.(*ast.TypeSpec)
+				pos := fset.Position(ts.Name.Pos())
+				decls = append(decls, Decl{
+					Keyword:  "type",
+					Ident:    ts.Name.Name,
+					Full:     NewTypeSignature(ts).Full,
+					Filename: pos.Filename,
+					Line:     pos.Line,
+					Col:      pos.Column,
+				})
+			}
+		}
+	}
+	return decls
+}
+
+// symbolScore returns the rank of the identifier for the given query. Higher
+// is better, zero means no match. The rank is, from best to worst: exact
+// match, case insensitive match, prefix, camelCase initials (i.e: "SH" for
+// "ServeHTTP"), substring and finally a fuzzy subsequence match.
+func symbolScore(query, ident string) int {
+	if query == "" {
+		return 1
+	}
+
+	lquery, lident := strings.ToLower(query), strings.ToLower(ident)
+
+	switch {
+	case query == ident:
+		return 1000
+	case lquery == lident:
+		return 900
+	case strings.HasPrefix(lident, lquery):
+		// shorter identifiers are closer to the query
+		return 700 + 100/(1+len(ident)-len(query))
+	case strings.HasPrefix(camelInitials(ident), strings.ToUpper(query)):
+		return 600
+	case strings.Contains(lident, lquery):
+		return 400 + 100/(1+strings.Index(lident, lquery))
+	}
+
+	// fuzzy subsequence match, consecutive characters are ranked higher
+	score, qi, prev := 0, 0, -2
+	lq := []rune(lquery)
+	for i, r := range []rune(lident) {
+		if qi == len(lq) {
+			break
+		}
+		if r != lq[qi] {
+			continue
+		}
+
+		score += 2
+		if prev == i-1 {
+			score += 3
+		}
+		prev = i
+		qi++
+	}
+
+	if qi != len(lq) {
+		return 0
+	}
+
+	return 1 + score*100/(2*len(lident)+3*len(lq))
+}
+
+// camelInitials returns the first letter of every camelCase word of the
+// identifier in upper case, i.e: "ServeHTTP" returns "SH" and "new_foo"
+// returns "NF".
+func camelInitials(ident string) string {
+	var initials []rune
+	runes := []rune(ident)
+	for i, r := range runes {
+		switch {
+		case r == '_':
+			continue
+		case i == 0, runes[i-1] == '_':
+			initials = append(initials, unicode.ToUpper(r))
+		case unicode.IsUpper(r) && !unicode.IsUpper(runes[i-1]):
+			initials = append(initials, r)
+		}
+	}
+	return string(initials)
+}
@@ -0,0 +1,128 @@
+package astcontext
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindSymbols(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":         "# generated\ngen/\n",
+		"server.go":          "package server\n\ntype Handler struct{}\n\nfunc (h *Handler) ServeHTTP() {}\n",
+		"sub/serve.go":       "package sub\n\nfunc Serve() {}\n\nfunc observe() {}\n",
+		"sub/skip.pb.go":     "package sub\n\nfunc ServeGenerated() {}\n",
+		"gen/gen.go":         "package gen\n\nfunc ServeGen() {}\n",
+		"vendor/v/v.go":      "package v\n\nfunc ServeVendor() {}\n",
+		"testdata/t.go":      "package t\n\nfunc ServeTestdata() {}\n",
+		".hidden/h.go":       "package h\n\nfunc ServeHidden() {}\n",
+		"_underscore/u.go":   "package u\n\nfunc ServeUnderscore() {}\n",
+		"broken/broken.go":   "package broken\n\nfunc ServeBroken() {}\n\nfunc (\n",
+		"sub/not_go_file.md": "func ServeMarkdown() {}\n",
+	}
+
+	for name, src := range files {
+		filename := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name string
+		opts SymbolOptions
+		want []string
+	}{
+		{
+			name: "rank",
+			opts: SymbolOptions{Query: "serve", Skip: []string{"*.pb.go"}},
+			want: []string{"Serve", "ServeHTTP", "ServeBroken", "observe"},
+		},
+		{
+			name: "camel case",
+			opts: SymbolOptions{Query: "SH"},
+			want: []string{"ServeHTTP"},
+		},
+		{
+			name: "fuzzy",
+			opts: SymbolOptions{Query: "hdlr"},
+			want: []string{"Handler"},
+		},
+		{
+			name: "page",
+			opts: SymbolOptions{Query: "serve", Page: 1, Limit: 2},
+			want: []string{"ServeBroken", "ServeGenerated"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.Root = root
+			symbols, err := FindSymbols(&tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var idents []string
+			for _, decl := range symbols.Decls {
+				idents = append(idents, decl.Ident)
+			}
+
+			if !reflect.DeepEqual(idents, tc.want) {
+				t.Errorf("wrong symbols:\nwant: %v\ngot:  %v", tc.want, idents)
+			}
+		})
+	}
+}
+
+func TestParser_RunWorkspaceModes(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"root.go":    "package root\n\n// TODO: root\nfunc Root() {}\n",
+		"sub/sub.go": "package sub\n\n// FIXME: sub\nfunc ServeSub() {}\n",
+	}
+
+	for name, src := range files {
+		filename := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	parser, err := NewParser(&ParserOptions{Dir: root, Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := parser.Run(&Query{Mode: "symbols", Symbol: "serve"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Symbols.Total != 1 || res.Symbols.Decls[0].Ident != "ServeSub" {
+		t.Errorf("wrong symbols: %+v", res.Symbols)
+	}
+
+	res, err = parser.Run(&Query{Mode: "todos"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Todos) != 1 {
+		t.Errorf("todos of the parsed directory only, got: %+v", res.Todos)
+	}
+
+	res, err = parser.Run(&Query{Mode: "todos", Recursive: true, Skip: []string{"root.go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Todos) != 1 || res.Todos[0].Marker != "FIXME" {
+		t.Errorf("wrong recursive todos: %+v", res.Todos)
+	}
+}
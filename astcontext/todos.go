@@ -0,0 +1,216 @@
+package astcontext
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// DefaultTodoMarkers are the markers of the "todos" mode if no markers are
+// specified
+var DefaultTodoMarkers = []string{"TODO", "FIXME", "XXX", "NOTE", "BUG"}
+
+// Todo specifies a single annotation of the "todos" mode, i.e:
+//
+//	// TODO(arslan): handle the error
+type Todo struct {
+	// Marker is the matched marker, i.e: "TODO"
+	Marker string `json:"marker" vim:"marker"`
+
+	// Author is the name in parentheses after the marker, i.e: "arslan".
+	// Empty if there is none.
+	Author string `json:"author" vim:"author"`
+
+	// Text is the text after the marker
+	Text string `json:"text" vim:"text"`
+
+	// Func is the name of the enclosing function declaration. Empty for
+	// annotations outside of functions.
+	Func string `json:"func" vim:"func"`
+
+	Filename string `json:"filename" vim:"filename"`
+	Line     int    `json:"line" vim:"line"`
+	Col      int    `json:"col" vim:"col"`
+}
+
+// TodoOptions defines the options of a workspace wide annotation search
+type TodoOptions struct {
+	// Root is the directory to be walked recursively
+	Root string
+
+	// Skip defines additional .gitignore like patterns of files and
+	// directories to be skipped, see SymbolOptions.Skip
+	Skip []string
+
+	// Markers are the markers to search for. If empty, DefaultTodoMarkers
+	// are used.
+	Markers []string
+}
+
+// Todos returns the annotations of the parsed files matching the given
+// markers, sorted by their position. If no markers are specified,
+// DefaultTodoMarkers are used. The source has to be parsed with comments.
+//
+// A marker only matches at the beginning of a comment line and has to be
+// followed by an optional author in parentheses and a colon, a space or the
+// end of the line, i.e: "TODO(arslan): foo", "FIXME: foo" or "XXX foo".
+func (p *Parser) Todos(markers []string) ([]Todo, error) {
+	if len(markers) == 0 {
+		markers = DefaultTodoMarkers
+	}
+
+	files, err := p.files()
+	if err != nil {
+		return nil, err
+	}
+
+	todos := []Todo{}
+	funcs := p.Funcs().Declarations()
+	for _, file := range files {
+		todos = append(todos, fileTodos(p.fset, file, funcs, markers)...)
+	}
+	return todos, nil
+}
+
+// FindTodos walks the given root recursively and returns the annotations of
+// all Go files, sorted by filename and position. Directories are skipped the
+// same as FindSymbols does.
+func FindTodos(opts *TodoOptions) ([]Todo, error) {
+	if opts == nil || opts.Root == "" {
+		return nil, errors.New("root directory is not specified")
+	}
+
+	markers := opts.Markers
+	if len(markers) == 0 {
+		markers = DefaultTodoMarkers
+	}
+
+	files, err := walkGoFiles(opts.Root, opts.Skip)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	// files which can't be read are skipped, files with syntax errors
+	// contribute the annotations that could be parsed
+	fset := token.NewFileSet()
+	results := make([][]Todo, len(files))
+	forEach(len(files), func(i int) {
+		file, _ := parser.ParseFile(fset, files[i], nil, parser.ParseComments|parser.SkipObjectResolution)
+		if file == nil {
+			return
+		}
+
+		funcs := collectFuncs(fset, []*ast.File{file}).Declarations()
+		results[i] = fileTodos(fset, file, funcs, markers)
+	})
+
+	todos := []Todo{}
+	for _, t := range results {
+		todos = append(todos, t...)
+	}
+	return todos, nil
+}
+
+// fileTodos returns the annotations of the given file. Funcs are the function
+// declarations used to find the enclosing function, they may contain the
+// functions of other files.
+func fileTodos(fset *token.FileSet, file *ast.File, funcs Funcs, markers []string) []Todo {
+	filename := fset.Position(file.Pos()).Filename
+	funcs = funcs.inFile(filename)
+
+	var todos []Todo
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			for _, line := range commentLines(c) {
+				todo, ok := parseTodo(line.text, markers)
+				if !ok {
+					continue
+				}
+
+				pos := fset.Position(c.Slash + token.Pos(line.offset))
+				todo.Filename = pos.Filename
+				todo.Line = pos.Line
+				todo.Col = pos.Column
+
+				if fn, err := funcs.EnclosingFunc(pos.Offset); err == nil {
+					todo.Func = fn.Signature.Name
+				}
+
+				todos = append(todos, todo)
+			}
+		}
+	}
+
+	return todos
+}
+
+// commentLine is a line of a comment without the comment markers and the
+// leading whitespace. Offset is the offset of the text in the comment.
+type commentLine struct {
+	text   string
+	offset int
+}
+
+// commentLines returns the lines of the given comment. The leading asterisks
+// of block comment lines are removed too.
+func commentLines(c *ast.Comment) []commentLine {
+	if strings.HasPrefix(c.Text, "//") {
+		text := c.Text[2:]
+		trimmed := strings.TrimLeft(text, " \t")
+		return []commentLine{{text: trimmed, offset: 2 + len(text) - len(trimmed)}}
+	}
+
+	var lines []commentLine
+	offset := 2
+	for _, text := range strings.Split(strings.TrimSuffix(c.Text[2:], "*/"), "\n") {
+		trimmed := strings.TrimLeft(text, " \t")
+		if strings.HasPrefix(trimmed, "*") {
+			trimmed = strings.TrimLeft(trimmed[1:], " \t")
+		}
+
+		lines = append(lines, commentLine{text: trimmed, offset: offset + len(text) - len(trimmed)})
+		offset += len(text) + 1
+	}
+	return lines
+}
+
+// parseTodo parses an annotation with one of the given markers at the
+// beginning of the line
+func parseTodo(line string, markers []string) (Todo, bool) {
+	for _, marker := range markers {
+		if !strings.HasPrefix(line, marker) {
+			continue
+		}
+
+		rest := line[len(marker):]
+		todo := Todo{Marker: marker}
+
+		if strings.HasPrefix(rest, "(") {
+			end := strings.Index(rest, ")")
+			if end < 0 {
+				continue
+			}
+			todo.Author = strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+		}
+
+		switch {
+		case rest == "":
+		case rest[0] == ':':
+			rest = rest[1:]
+		case rest[0] == ' ' || rest[0] == '\t':
+		default:
+			// part of a longer word, i.e: TODOS
+			continue
+		}
+
+		todo.Text = strings.TrimSpace(rest)
+		return todo, true
+	}
+
+	return Todo{}, false
+}
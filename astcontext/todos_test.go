@@ -0,0 +1,111 @@
+package astcontext
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const todoSrc = `package main
+
+// TODO(arslan): package level
+var x = 1
+
+// Foo does things.
+//
+// FIXME: broken
+func Foo() {
+	f := func() {
+		// XXX inside a literal
+	}
+	/*
+	 * NOTE(fatih):
+	 *   block comment
+	 */
+	f()
+	// TODOS is not a marker
+	// see TODO: not at the beginning
+}
+
+func Bar() {} // BUG(someone): trailing, outside of the function
+`
+
+func TestParser_Todos(t *testing.T) {
+	parser, err := NewParser(&ParserOptions{Src: []byte(todoSrc), Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Todo{
+		{Marker: "TODO", Author: "arslan", Text: "package level", Filename: "src.go", Line: 3, Col: 4},
+		{Marker: "FIXME", Text: "broken", Func: "Foo", Filename: "src.go", Line: 8, Col: 4},
+		{Marker: "XXX", Text: "inside a literal", Func: "Foo", Filename: "src.go", Line: 11, Col: 6},
+		{Marker: "NOTE", Author: "fatih", Func: "Foo", Filename: "src.go", Line: 14, Col: 5},
+		{Marker: "BUG", Author: "someone", Text: "trailing, outside of the function", Filename: "src.go", Line: 22, Col: 18},
+	}
+
+	res, err := parser.Run(&Query{Mode: "todos"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(res.Todos, want) {
+		t.Errorf("wrong todos\nwant: %+v\ngot:  %+v", want, res.Todos)
+	}
+
+	res, err = parser.Run(&Query{Mode: "todos", Markers: []string{"NOTE", "BUG"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Todos) != 2 || res.Todos[0].Marker != "NOTE" || res.Todos[1].Marker != "BUG" {
+		t.Errorf("wrong todos for custom markers: %+v", res.Todos)
+	}
+}
+
+func TestFindTodos(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a.go":              "package a\n\n// TODO: a\nfunc A() {\n}\n",
+		"sub/b.go":          "package sub\n\nfunc B() {\n\t// FIXME(x): b\n}\n",
+		"vendor/v.go":       "package v\n\n// TODO: vendored\n",
+		"skipped/s.go":      "package s\n\n// TODO: skipped\n",
+		"sub/syntax_err.go": "package sub\n\n// TODO: still found\nfunc {\n",
+	}
+
+	for name, src := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	todos, err := FindTodos(&TodoOptions{Root: root, Skip: []string{"skipped"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, todo := range todos {
+		rel, _ := filepath.Rel(root, todo.Filename)
+		got = append(got, filepath.ToSlash(rel)+" "+todo.Marker+" "+todo.Func+" "+todo.Text)
+	}
+
+	want := []string{
+		"a.go TODO A a",
+		"sub/b.go FIXME B b",
+		"sub/syntax_err.go TODO  still found",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong todos\nwant: %q\ngot:  %q", want, got)
+	}
+
+	if _, err := FindTodos(&TodoOptions{}); err == nil {
+		t.Error("missing root should fail")
+	}
+}
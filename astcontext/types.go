@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"go/ast"
+	"go/token"
 	"go/types"
 )
 
@@ -52,32 +53,55 @@ func NewTypeSignature(node *ast.TypeSpec) *TypeSignature {
 }
 
 // Types returns a list of Type's from the parsed source. Type's are sorted
-// according to the order of Go type declaration in the given source.
+// according to the order of Go type declaration in the given source. The
+// types are collected only once, each call returns a new copy of the list.
 func (p *Parser) Types() Types {
-	var files []*ast.File
-	if p.file != nil {
-		files = append(files, p.file)
-	}
+	p.typesOnce.Do(func() {
+		p.types = p.collectTypes()
+	})
 
-	if p.pkgs != nil {
-		for _, pkg := range p.pkgs {
-			for _, f := range pkg.Files {
-				files = append(files, f)
-			}
+	typs := make(Types, len(p.types))
+	copy(typs, p.types)
+	return typs
+}
+
+func (p *Parser) collectTypes() Types {
+	if p.cached != nil {
+		var typs Types
+		for _, e := range p.cached {
+			typs = append(typs, e.Types...)
 		}
+		return typs
 	}
 
+	// only the files of a cached directory are parsed lazily and can fail
+	files, _ := p.files()
+	return collectTypes(p.fset, files)
+}
+
+// collectTypes returns the type declarations of the given files
+func collectTypes(fset *token.FileSet, files []*ast.File) Types {
 	var typs []*Type
+	var gen *ast.GenDecl
 	inspect := func(n ast.Node) bool {
 		switch x := n.(type) {
+		case *ast.GenDecl:
+			gen = x
 		case *ast.TypeSpec:
 			tp := &Type{
-				TypePos: ToPosition(p.fset.Position(x.Name.Pos())),
+				TypePos: ToPosition(fset.Position(x.Name.Pos())),
 				node:    x,
 			}
 
-			if x.Doc != nil {
-				tp.Doc = ToPosition(p.fset.Position(x.Doc.Pos()))
+			// the doc comment of a declaration without parentheses belongs
+			// to the GenDecl
+			doc := x.Doc
+			if doc == nil && gen != nil && !gen.Lparen.IsValid() {
+				doc = gen.Doc
+			}
+
+			if doc != nil {
+				tp.Doc = ToPosition(fset.Position(doc.Pos()))
 			}
 
 			tp.Signature = NewTypeSignature(x)
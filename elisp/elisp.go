@@ -0,0 +1,215 @@
+// Package elisp provides an encoder for structured data in Emacs Lisp. The
+// output can be consumed directly with the Emacs Lisp reader (read).
+//
+// Structs and maps are encoded as association lists with symbol keys, arrays
+// and slices as vectors, booleans as t and nil and nil values as nil.
+//
+// Struct fields are named by the "elisp" struct tag. If a field has no
+// "elisp" tag, the "json" tag is used. The tag format is the same as of the
+// encoding/json package, i.e: `elisp:"name,omitempty"`.
+package elisp
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/fatih/motion/internal/tags"
+)
+
+// Marshal returns the Emacs Lisp encoding of v.
+func Marshal(x interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := marshal(&buf, reflect.ValueOf(x)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func marshal(buf *bytes.Buffer, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Invalid:
+		buf.WriteString("nil")
+
+	case reflect.Bool:
+		if v.Bool() {
+			buf.WriteString("t")
+		} else {
+			buf.WriteString("nil")
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		fmt.Fprint(buf, v.Interface())
+
+	case reflect.Float32, reflect.Float64:
+		writeFloat(buf, v.Float())
+
+	case reflect.String:
+		writeString(buf, v.String())
+
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("nil")
+			return nil
+		}
+		return marshal(buf, v.Elem())
+
+	case reflect.Array, reflect.Slice:
+		buf.WriteByte('[')
+		n := v.Len()
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			if err := marshal(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("non-string key type in %s", v.Type())
+		}
+
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+		if len(keys) == 0 {
+			buf.WriteString("nil")
+			return nil
+		}
+
+		buf.WriteByte('(')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			if err := writePair(buf, k.String(), v.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(')')
+
+	case reflect.Struct:
+		empty := true
+		for _, f := range tags.Fields(v.Type(), "elisp", "json") {
+			fv, ok := f.Value(v)
+			if !ok {
+				continue
+			}
+
+			if empty {
+				buf.WriteByte('(')
+				empty = false
+			} else {
+				buf.WriteByte(' ')
+			}
+
+			if err := writePair(buf, f.Name, fv); err != nil {
+				return err
+			}
+		}
+
+		if empty {
+			buf.WriteString("nil")
+		} else {
+			buf.WriteByte(')')
+		}
+
+	case reflect.Complex64, reflect.Complex128,
+		reflect.UnsafePointer,
+		reflect.Func,
+		reflect.Chan:
+		return fmt.Errorf("unsupported type: %s", v.Type())
+	}
+
+	return nil
+}
+
+// writePair writes a cons cell of an association list, i.e: (key . value)
+func writePair(buf *bytes.Buffer, key string, v reflect.Value) error {
+	buf.WriteByte('(')
+	writeSymbol(buf, key)
+	buf.WriteString(" . ")
+	if err := marshal(buf, v); err != nil {
+		return err
+	}
+	buf.WriteByte(')')
+	return nil
+}
+
+// writeSymbol writes s as a symbol, escaping the characters which have a
+// special meaning for the reader.
+func writeSymbol(buf *bytes.Buffer, s string) {
+	if s == "" {
+		// the reader returns the interned empty symbol for ##
+		buf.WriteString("##")
+		return
+	}
+
+	// a symbol which looks like a number needs to be escaped
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		buf.WriteByte('\\')
+	}
+
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteRune(r)
+	}
+}
+
+// writeString writes s as a double quoted string. Non-ASCII characters are
+// written as is, control characters are escaped.
+func writeString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r == '\r':
+			buf.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(buf, `\u%04x`, r)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// writeFloat writes f in a format the reader reads as a float, i.e: 1.0
+// instead of 1, which is an integer.
+func writeFloat(buf *bytes.Buffer, f float64) {
+	switch {
+	case math.IsNaN(f):
+		buf.WriteString("0.0e+NaN")
+		return
+	case math.IsInf(f, 1):
+		buf.WriteString("1.0e+INF")
+		return
+	case math.IsInf(f, -1):
+		buf.WriteString("-1.0e+INF")
+		return
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	buf.WriteString(s)
+}
@@ -0,0 +1,59 @@
+package elisp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	type pos struct {
+		Line int    `json:"line"`
+		Col  int    `elisp:"column" json:"col"`
+		File string `json:"file,omitempty"`
+		Skip bool   `json:"-"`
+	}
+
+	type message struct {
+		pos
+		Text string `json:"text"`
+	}
+
+	cases := []struct {
+		in   interface{}
+		want string
+	}{
+		{nil, `nil`},
+		{true, `t`},
+		{false, `nil`},
+		{42, `42`},
+		{1.0, `1.0`},
+		{1.5, `1.5`},
+		{math.Inf(1), `1.0e+INF`},
+		{"a \"b\" \\ ü\n\x01", `"a \"b\" \\ ü\n\u0001"`},
+		{[]int{1, 2}, `[1 2]`},
+		{[]string{}, `[]`},
+		{map[string]int{"b": 2, "a": 1}, `((a . 1) (b . 2))`},
+		{map[string]int{}, `nil`},
+		{map[string]int{"with space": 1, "1": 2}, `((\1 . 2) (with\ space . 1))`},
+		{pos{Line: 1, Col: 2}, `((line . 1) (column . 2))`},
+		{&pos{Line: 1, File: "a.go"}, `((line . 1) (column . 0) (file . "a.go"))`},
+		{message{pos{Line: 1}, "a"}, `((line . 1) (column . 0) (text . "a"))`},
+		{struct{}{}, `nil`},
+		{(*pos)(nil), `nil`},
+	}
+
+	for _, tc := range cases {
+		out, err := Marshal(tc.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(out) != tc.want {
+			t.Errorf("Marshal(%#v)\nwant: %s\ngot:  %s", tc.in, tc.want, out)
+		}
+	}
+
+	if _, err := Marshal(complex(1, 2)); err == nil {
+		t.Error("complex numbers should not be supported")
+	}
+}
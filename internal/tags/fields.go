@@ -0,0 +1,286 @@
+package tags
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// from $GOROOT/src/encoding/json/encode.go
+
+// Field represents a single field found in a struct.
+type Field struct {
+	Name      string
+	Index     []int
+	Type      reflect.Type
+	OmitEmpty bool
+	Quoted    bool
+
+	tag bool // name came from a struct tag
+}
+
+// Value returns the value of the field in the struct v. It returns false if
+// the field should be omitted, i.e: it's empty and has the omitempty option,
+// or it's promoted from a nil embedded struct pointer.
+func (f *Field) Value(v reflect.Value) (reflect.Value, bool) {
+	for _, i := range f.Index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+
+	if !v.IsValid() || f.OmitEmpty && IsEmptyValue(v) {
+		return reflect.Value{}, false
+	}
+	return v, true
+}
+
+type fieldsKey struct {
+	typ  reflect.Type
+	keys string
+}
+
+var fieldCache sync.Map // map[fieldsKey][]Field
+
+// Fields returns the fields of the struct type t that an encoder should
+// recognize, flattening embedded structs the same way as encoding/json. The
+// name and options of a field are read from the first of the given struct tag
+// keys the field has. The result is cached.
+func Fields(t reflect.Type, keys ...string) []Field {
+	key := fieldsKey{typ: t, keys: strings.Join(keys, ",")}
+	if f, ok := fieldCache.Load(key); ok {
+		return f.([]Field)
+	}
+	f, _ := fieldCache.LoadOrStore(key, typeFields(t, keys))
+	return f.([]Field)
+}
+
+// lookup returns the tag of the first of the given keys sf has.
+func lookup(sf reflect.StructField, keys []string) string {
+	for _, key := range keys {
+		if tag, ok := sf.Tag.Lookup(key); ok {
+			return tag
+		}
+	}
+	return ""
+}
+
+// typeFields returns a list of fields that the encoder should recognize for
+// the given type. The algorithm is breadth-first search over the set of
+// structs to include - the top struct and then any reachable anonymous
+// structs.
+func typeFields(t reflect.Type, keys []string) []Field {
+	// Anonymous fields to explore at the current level and the next.
+	current := []Field{}
+	next := []Field{{Type: t}}
+
+	// Count of queued names for current level and the next.
+	var count, nextCount map[reflect.Type]int
+
+	// Types already visited at an earlier level.
+	visited := map[reflect.Type]bool{}
+
+	var fields []Field
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+
+		for _, f := range current {
+			if visited[f.Type] {
+				continue
+			}
+			visited[f.Type] = true
+
+			// Scan f.Type for fields to include.
+			for i := 0; i < f.Type.NumField(); i++ {
+				sf := f.Type.Field(i)
+				if sf.Anonymous {
+					t := sf.Type
+					if t.Kind() == reflect.Ptr {
+						t = t.Elem()
+					}
+					if !sf.IsExported() && t.Kind() != reflect.Struct {
+						// Ignore embedded fields of unexported non-struct types.
+						continue
+					}
+					// Do not ignore embedded fields of unexported struct types
+					// since they may have exported fields.
+				} else if !sf.IsExported() {
+					// Ignore unexported non-embedded fields.
+					continue
+				}
+
+				tag := lookup(sf, keys)
+				if tag == "-" {
+					continue
+				}
+
+				name, opts := Parse(tag)
+				if !IsValid(name) {
+					name = ""
+				}
+
+				index := make([]int, len(f.Index)+1)
+				copy(index, f.Index)
+				index[len(f.Index)] = i
+
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					// Follow pointer.
+					ft = ft.Elem()
+				}
+
+				// Only strings, floats, integers, and booleans can be quoted.
+				quoted := false
+				if opts.Contains("string") {
+					switch ft.Kind() {
+					case reflect.Bool,
+						reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+						reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+						reflect.Float32, reflect.Float64,
+						reflect.String:
+						quoted = true
+					}
+				}
+
+				// Record found field and index sequence.
+				if name != "" || !sf.Anonymous || ft.Kind() != reflect.Struct {
+					tagged := name != ""
+					if name == "" {
+						name = sf.Name
+					}
+
+					fields = append(fields, Field{
+						Name:      name,
+						Index:     index,
+						Type:      ft,
+						OmitEmpty: opts.Contains("omitempty"),
+						Quoted:    quoted,
+						tag:       tagged,
+					})
+
+					if count[f.Type] > 1 {
+						// If there were multiple instances, add a second,
+						// so that the annihilation code will see a duplicate.
+						// It only cares about the distinction between 1 and 2,
+						// so don't bother generating any more copies.
+						fields = append(fields, fields[len(fields)-1])
+					}
+					continue
+				}
+
+				// Record new anonymous struct to explore in next round.
+				nextCount[ft]++
+				if nextCount[ft] == 1 {
+					next = append(next, Field{Name: ft.Name(), Index: index, Type: ft})
+				}
+			}
+		}
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		x := fields
+		// sort field by name, breaking ties with depth, then
+		// breaking ties with "name came from a struct tag", then
+		// breaking ties with index sequence.
+		if x[i].Name != x[j].Name {
+			return x[i].Name < x[j].Name
+		}
+		if len(x[i].Index) != len(x[j].Index) {
+			return len(x[i].Index) < len(x[j].Index)
+		}
+		if x[i].tag != x[j].tag {
+			return x[i].tag
+		}
+		return indexLess(x[i].Index, x[j].Index)
+	})
+
+	// Delete all fields that are hidden by the Go rules for embedded fields,
+	// except that fields with struct tags are promoted.
+
+	// The fields are sorted in primary order of name, secondary order
+	// of field index length. Loop over names; for each name, delete
+	// hidden fields by choosing the one dominant field that survives.
+	out := fields[:0]
+	for advance, i := 0, 0; i < len(fields); i += advance {
+		// One iteration per name.
+		// Find the sequence of fields with the name of this first field.
+		fi := fields[i]
+		name := fi.Name
+		for advance = 1; i+advance < len(fields); advance++ {
+			fj := fields[i+advance]
+			if fj.Name != name {
+				break
+			}
+		}
+		if advance == 1 { // Only one field with this name
+			out = append(out, fi)
+			continue
+		}
+		dominant, ok := dominantField(fields[i : i+advance])
+		if ok {
+			out = append(out, dominant)
+		}
+	}
+
+	fields = out
+	sort.Slice(fields, func(i, j int) bool {
+		return indexLess(fields[i].Index, fields[j].Index)
+	})
+
+	return fields
+}
+
+// dominantField looks through the fields, all of which are known to have the
+// same name, to find the single field that dominates the others using Go's
+// embedding rules, modified by the presence of struct tags. If there are
+// multiple top-level fields, the boolean will be false: This condition is an
+// error in Go and we skip all the fields.
+func dominantField(fields []Field) (Field, bool) {
+	// The fields are sorted in increasing index-length order, then by presence
+	// of tag. That means that the first field is the dominant one. We need
+	// only check for error cases: two fields at top level, either both tagged
+	// or neither tagged.
+	if len(fields) > 1 && len(fields[0].Index) == len(fields[1].Index) && fields[0].tag == fields[1].tag {
+		return Field{}, false
+	}
+	return fields[0], true
+}
+
+func indexLess(a, b []int) bool {
+	for k, x := range a {
+		if k >= len(b) {
+			return false
+		}
+		if x != b[k] {
+			return x < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// IsEmptyValue reports whether v is empty, as defined by the omitempty option.
+//
+// from $GOROOT/src/encoding/json/encode.go
+func IsEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
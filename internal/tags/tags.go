@@ -1,4 +1,7 @@
-package vim
+// Package tags resolves the struct fields of the vim, elisp and lua encoders
+// from their struct tags. The tags and embedded structs follow the rules of
+// encoding/json.
+package tags
 
 import (
 	"strings"
@@ -7,23 +10,23 @@ import (
 
 // from $GOROOT/src/encoding/json/tags.go
 
-// tagOptions is the string following a comma in a struct field's "json"
+// Options is the string following a comma in a struct field's "json"
 // tag, or the empty string. It does not include the leading comma.
-type tagOptions string
+type Options string
 
-// parseTag splits a struct field's json tag into its name and
+// Parse splits a struct field's json tag into its name and
 // comma-separated options.
-func parseTag(tag string) (string, tagOptions) {
+func Parse(tag string) (string, Options) {
 	if idx := strings.Index(tag, ","); idx != -1 {
-		return tag[:idx], tagOptions(tag[idx+1:])
+		return tag[:idx], Options(tag[idx+1:])
 	}
-	return tag, tagOptions("")
+	return tag, Options("")
 }
 
 // Contains reports whether a comma-separated list of options
 // contains a particular substr flag. substr must be surrounded by a
 // string boundary or commas.
-func (o tagOptions) Contains(optionName string) bool {
+func (o Options) Contains(optionName string) bool {
 	if len(o) == 0 {
 		return false
 	}
@@ -42,7 +45,8 @@ func (o tagOptions) Contains(optionName string) bool {
 	return false
 }
 
-func isValidTag(s string) bool {
+// IsValid reports whether the given name of a tag is valid
+func IsValid(s string) bool {
 	if s == "" {
 		return false
 	}
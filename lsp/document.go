@@ -0,0 +1,159 @@
+package lsp
+
+import (
+	"errors"
+	"go/token"
+	"net/url"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/fatih/motion/astcontext"
+)
+
+// document is a text document opened by the client
+type document struct {
+	uri     string
+	version int
+	text    string
+
+	// lines contains the byte offsets of the beginning of each line
+	lines []int
+
+	// parser is created lazily on the first query and is updated by the
+	// following changes
+	parser *astcontext.Parser
+}
+
+func newDocument(uri string, version int, text string) *document {
+	d := &document{uri: uri, version: version}
+	d.setText(text)
+	return d
+}
+
+func (d *document) setText(text string) {
+	d.text = text
+	d.parser = nil
+
+	d.lines = d.lines[:0]
+	d.lines = append(d.lines, 0)
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			d.lines = append(d.lines, i+1)
+		}
+	}
+}
+
+// applyChange applies the given change to the document text
+func (d *document) applyChange(change TextDocumentContentChangeEvent) error {
+	if change.Range == nil {
+		d.setText(change.Text)
+		return nil
+	}
+
+	start, err := d.offset(change.Range.Start)
+	if err != nil {
+		return err
+	}
+
+	end, err := d.offset(change.Range.End)
+	if err != nil {
+		return err
+	}
+
+	if end < start {
+		return errors.New("invalid range: end is before start")
+	}
+
+	// the parser is updated incrementally, it's created again on the next
+	// query if the new text can't be parsed
+	parser := d.parser
+	d.setText(d.text[:start] + change.Text + d.text[end:])
+	if parser != nil && parser.Edit(astcontext.TextEdit{Start: start, End: end, Text: change.Text}) == nil {
+		d.parser = parser
+	}
+	return nil
+}
+
+// filename returns the file path of the document URI. If the URI is not a
+// file URI, the URI itself is returned.
+func (d *document) filename() string {
+	u, err := url.Parse(d.uri)
+	if err != nil || u.Scheme != "file" {
+		return d.uri
+	}
+	return u.Path
+}
+
+// parse returns the parser of the current text of the document
+func (d *document) parse() (*astcontext.Parser, error) {
+	if d.parser != nil {
+		return d.parser, nil
+	}
+
+	p, err := astcontext.NewParser(&astcontext.ParserOptions{
+		File:     d.filename(),
+		Src:      []byte(d.text),
+		Comments: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d.parser = p
+	return p, nil
+}
+
+// offset returns the byte offset of the given position. Characters of the
+// position are counted in UTF-16 code units.
+func (d *document) offset(pos Position) (int, error) {
+	if pos.Line < 0 || pos.Line >= len(d.lines) || pos.Character < 0 {
+		return 0, errors.New("position is outside of the document")
+	}
+
+	offset := d.lines[pos.Line]
+	for chars := 0; chars < pos.Character; {
+		if offset >= len(d.text) || d.text[offset] == '\n' {
+			// clients are allowed to send positions after the line end
+			break
+		}
+
+		r, size := utf8.DecodeRuneInString(d.text[offset:])
+		chars += utf16Len(r)
+		offset += size
+	}
+
+	return offset, nil
+}
+
+// position returns the position of the given byte offset
+func (d *document) position(offset int) Position {
+	if offset > len(d.text) {
+		offset = len(d.text)
+	}
+
+	// index of the first line starting after offset, minus one
+	line := sort.SearchInts(d.lines, offset+1) - 1
+
+	chars := 0
+	for _, r := range d.text[d.lines[line]:offset] {
+		chars += utf16Len(r)
+	}
+
+	return Position{Line: line, Character: chars}
+}
+
+// nodeRange returns the range between the given token positions
+func (d *document) nodeRange(fset *token.FileSet, start, end token.Pos) Range {
+	return Range{
+		Start: d.position(fset.Position(start).Offset),
+		End:   d.position(fset.Position(end).Offset),
+	}
+}
+
+// utf16Len returns the number of UTF-16 code units to encode r
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,71 @@
+package lsp
+
+import "testing"
+
+func TestDocument_Position(t *testing.T) {
+	doc := newDocument(testURI, 1, "ab\nü😀c\n")
+
+	cases := []struct {
+		offset int
+		pos    Position
+	}{
+		{0, Position{0, 0}},
+		{2, Position{0, 2}},
+		{3, Position{1, 0}},
+		{5, Position{1, 1}}, // ü is 2 bytes, 1 UTF-16 unit
+		{9, Position{1, 3}}, // 😀 is 4 bytes, 2 UTF-16 units
+		{11, Position{2, 0}},
+	}
+
+	for _, tc := range cases {
+		if got := doc.position(tc.offset); got != tc.pos {
+			t.Errorf("position(%d) = %v, want %v", tc.offset, got, tc.pos)
+		}
+
+		got, err := doc.offset(tc.pos)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != tc.offset {
+			t.Errorf("offset(%v) = %d, want %d", tc.pos, got, tc.offset)
+		}
+	}
+
+	if _, err := doc.offset(Position{Line: 5}); err == nil {
+		t.Error("position outside of the document should fail")
+	}
+}
+
+func TestDocument_ApplyChange(t *testing.T) {
+	doc := newDocument(testURI, 1, "package main\n\nfunc foo() {\n}\n")
+	p, err := doc.parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	change := func(line, char int, text string) {
+		t.Helper()
+		pos := Position{Line: line, Character: char}
+		if err := doc.applyChange(TextDocumentContentChangeEvent{Range: &Range{Start: pos, End: pos}, Text: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the parser is updated incrementally
+	change(3, 0, "\tprintln()\n")
+	if doc.parser != p {
+		t.Fatal("parser should be kept")
+	}
+
+	fn := p.Funcs()[0]
+	if fn.Rbrace.Line != 5 {
+		t.Errorf("wrong closing brace line: %d", fn.Rbrace.Line)
+	}
+
+	// invalid text drops the parser
+	change(3, 0, "func {")
+	if doc.parser != nil {
+		t.Error("parser should be dropped")
+	}
+}
@@ -0,0 +1,93 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// maxContentLength is the maximum size of the content of a message. Larger
+// messages are rejected instead of allocating the size the client claims.
+const maxContentLength = 64 << 20
+
+// conn reads and writes JSON-RPC messages with the base protocol framing of
+// LSP, i.e: a "Content-Length" header followed by the JSON content.
+type conn struct {
+	r *bufio.Reader
+
+	mu sync.Mutex // protects w
+	w  io.Writer
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{r: bufio.NewReader(r), w: w}
+}
+
+// read reads the next message. It returns io.EOF if the stream is closed
+// between two messages.
+func (c *conn) read() (*message, error) {
+	header, err := textproto.NewReader(c.r).ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("couldn't read header: %s", err)
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length <= 0 {
+		return nil, &Error{
+			Code:    CodeParseError,
+			Message: fmt.Sprintf("invalid Content-Length header: %q", header.Get("Content-Length")),
+		}
+	}
+
+	if length > maxContentLength {
+		// skip the content without buffering it, so the next message can
+		// be read
+		if _, err := io.CopyN(io.Discard, c.r, int64(length)); err != nil {
+			return nil, fmt.Errorf("couldn't read content: %s", err)
+		}
+
+		return nil, &Error{
+			Code:    CodeParseError,
+			Message: fmt.Sprintf("content of %d bytes exceeds the limit of %d bytes", length, maxContentLength),
+		}
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(c.r, content); err != nil {
+		return nil, fmt.Errorf("couldn't read content: %s", err)
+	}
+
+	msg := &message{}
+	if err := json.Unmarshal(content, msg); err != nil {
+		return nil, &Error{Code: CodeParseError, Message: err.Error()}
+	}
+
+	return msg, nil
+}
+
+// write writes the given message
+func (c *conn) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	content, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(content)); err != nil {
+		return err
+	}
+
+	_, err = c.w.Write(content)
+	return err
+}
@@ -0,0 +1,59 @@
+package lsp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// zeros is a reader of endless zero bytes
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func TestConn_ReadContentLength(t *testing.T) {
+	valid := "Content-Length: 17\r\n\r\n{\"method\":\"exit\"}"
+
+	cases := []struct {
+		name string
+		r    io.Reader
+	}{
+		{"missing", strings.NewReader("Content-Type: foo\r\n\r\n" + valid)},
+		{"negative", strings.NewReader("Content-Length: -1\r\n\r\n" + valid)},
+		{"invalid", strings.NewReader("Content-Length: foo\r\n\r\n" + valid)},
+		{"too large", io.MultiReader(
+			strings.NewReader(fmt.Sprintf("Content-Length: %d\r\n\r\n", maxContentLength+1)),
+			io.LimitReader(zeros{}, maxContentLength+1),
+			strings.NewReader(valid),
+		)},
+	}
+
+	for _, tc := range cases {
+		c := newConn(tc.r, io.Discard)
+
+		_, err := c.read()
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) || rpcErr.Code != CodeParseError {
+			t.Errorf("%s: expected a parse error, got: %v", tc.name, err)
+			continue
+		}
+
+		// the stream is still usable after a rejected message
+		msg, err := c.read()
+		if err != nil {
+			t.Errorf("%s: couldn't read the next message: %s", tc.name, err)
+			continue
+		}
+
+		if msg.Method != "exit" {
+			t.Errorf("%s: wrong method of the next message: %q", tc.name, msg.Method)
+		}
+	}
+}
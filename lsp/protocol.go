@@ -0,0 +1,214 @@
+package lsp
+
+import "encoding/json"
+
+// The types below are a subset of the Language Server Protocol specification
+// https://microsoft.github.io/language-server-protocol/specification
+
+// Position is a zero based line and character offset. Characters are counted
+// in UTF-16 code units.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a range in a text document, End is exclusive.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// TextDocumentIdentifier identifies a text document by its URI
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+// TextDocumentItem is a text document transferred from the client
+type TextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+// VersionedTextDocumentIdentifier identifies a specific version of a text
+// document
+type VersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+}
+
+// TextDocumentContentChangeEvent describes a change of a text document. If
+// Range is nil, Text is the full content of the document.
+type TextDocumentContentChangeEvent struct {
+	Range *Range `json:"range,omitempty"`
+	Text  string `json:"text"`
+}
+
+// InitializeParams are the params of the "initialize" request. Motion doesn't
+// depend on any client capability, they are not decoded.
+type InitializeParams struct {
+	ProcessID *int   `json:"processId"`
+	RootURI   string `json:"rootUri,omitempty"`
+}
+
+// InitializeResult is the result of the "initialize" request
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+	ServerInfo   ServerInfo         `json:"serverInfo"`
+}
+
+// ServerInfo describes the server
+type ServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// TextDocumentSyncKind defines how the client syncs document changes
+type TextDocumentSyncKind int
+
+// Supported sync kinds
+const (
+	SyncNone        TextDocumentSyncKind = 0
+	SyncFull        TextDocumentSyncKind = 1
+	SyncIncremental TextDocumentSyncKind = 2
+)
+
+// ServerCapabilities defines the capabilities provided by the server
+type ServerCapabilities struct {
+	TextDocumentSync       TextDocumentSyncKind `json:"textDocumentSync"`
+	DocumentSymbolProvider bool                 `json:"documentSymbolProvider"`
+	SelectionRangeProvider bool                 `json:"selectionRangeProvider"`
+	FoldingRangeProvider   bool                 `json:"foldingRangeProvider"`
+}
+
+// DidOpenTextDocumentParams are the params of "textDocument/didOpen"
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+// DidChangeTextDocumentParams are the params of "textDocument/didChange"
+type DidChangeTextDocumentParams struct {
+	TextDocument   VersionedTextDocumentIdentifier  `json:"textDocument"`
+	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+// DidCloseTextDocumentParams are the params of "textDocument/didClose"
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// DocumentSymbolParams are the params of "textDocument/documentSymbol"
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// SymbolKind is the kind of a symbol
+type SymbolKind int
+
+// Symbol kinds used by motion
+const (
+	SymbolClass     SymbolKind = 5
+	SymbolMethod    SymbolKind = 6
+	SymbolInterface SymbolKind = 11
+	SymbolFunction  SymbolKind = 12
+	SymbolVariable  SymbolKind = 13
+	SymbolConstant  SymbolKind = 14
+	SymbolStruct    SymbolKind = 23
+)
+
+// DocumentSymbol represents a symbol of a document, such as a function or a
+// type. Methods are children of their receiver types.
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           SymbolKind       `json:"kind"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+// SelectionRangeParams are the params of "textDocument/selectionRange"
+type SelectionRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Positions    []Position             `json:"positions"`
+}
+
+// SelectionRange is a range around a position, Parent contains it.
+type SelectionRange struct {
+	Range  Range           `json:"range"`
+	Parent *SelectionRange `json:"parent,omitempty"`
+}
+
+// FoldingRangeParams are the params of "textDocument/foldingRange"
+type FoldingRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// FoldingRange is a foldable range of lines
+type FoldingRange struct {
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	Kind      string `json:"kind,omitempty"` // one of: comment, imports, region
+}
+
+// MotionParams are the params of the custom "motion/enclosing",
+// "motion/next" and "motion/prev" requests
+type MotionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+	Shift        int                    `json:"shift,omitempty"`
+}
+
+// LogMessageParams are the params of the "window/logMessage" notification
+type LogMessageParams struct {
+	Type    MessageType `json:"type"`
+	Message string      `json:"message"`
+}
+
+// MessageType is the type of a logged message
+type MessageType int
+
+// Message types
+const (
+	MessageError   MessageType = 1
+	MessageWarning MessageType = 2
+	MessageInfo    MessageType = 3
+	MessageLog     MessageType = 4
+)
+
+// message is a JSON-RPC 2.0 request, response or notification
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *Error           `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error
+type Error struct {
+	Code    int        `json:"code"`
+	Message string     `json:"message"`
+	Data    *ErrorData `json:"data,omitempty"`
+}
+
+// ErrorData is the data of a failed motion request
+type ErrorData struct {
+	// Code is one of the astcontext.Code constants
+	Code string `json:"code"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// JSON-RPC and LSP error codes
+const (
+	CodeParseError           = -32700
+	CodeInvalidRequest       = -32600
+	CodeMethodNotFound       = -32601
+	CodeInvalidParams        = -32602
+	CodeInternalError        = -32603
+	CodeServerNotInitialized = -32002
+	CodeRequestFailed        = -32803
+)
@@ -0,0 +1,445 @@
+// Package lsp provides a Language Server Protocol front-end for motion. It
+// implements a focused subset of the protocol over a single stream, backed by
+// astcontext.Parser.
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+
+	"github.com/fatih/motion/astcontext"
+)
+
+// Server is a Language Server Protocol server. It handles the messages of a
+// single client sequentially.
+type Server struct {
+	conn *conn
+
+	// docs contains the documents opened by the client, keyed by URI
+	docs map[string]*document
+
+	initialized bool
+	shutdown    bool
+}
+
+// NewServer returns a new Server
+func NewServer() *Server {
+	return &Server{docs: make(map[string]*document)}
+}
+
+// Serve reads the messages of the client from r and writes the responses to
+// w until the client sends the "exit" notification. It returns an error if
+// the client exits without requesting a shutdown or if the stream is broken.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.conn = newConn(r, w)
+
+	for {
+		msg, err := s.conn.read()
+		if err == io.EOF {
+			return errors.New("connection closed before exit")
+		}
+
+		var rpcErr *Error
+		if errors.As(err, &rpcErr) {
+			if err := s.conn.write(&message{Error: rpcErr, ID: nullID()}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit without shutdown")
+			}
+			return nil
+		}
+
+		result, err := s.handle(msg)
+
+		// notifications don't have responses, their errors are logged to
+		// the client instead
+		if msg.ID == nil {
+			if err != nil {
+				if err := s.logError(msg.Method, err); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		resp := &message{ID: msg.ID}
+		if err != nil {
+			if !errors.As(err, &rpcErr) {
+				rpcErr = &Error{
+					Code:    CodeRequestFailed,
+					Message: err.Error(),
+					Data:    &ErrorData{Code: astcontext.ErrorCode(err)},
+				}
+			}
+			resp.Error = rpcErr
+		} else {
+			resp.Result, err = json.Marshal(result)
+			if err != nil {
+				resp.Result = nil
+				resp.Error = &Error{Code: CodeInternalError, Message: err.Error()}
+			}
+		}
+
+		if err := s.conn.write(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// handle handles a single request or notification
+func (s *Server) handle(msg *message) (interface{}, error) {
+	if !s.initialized && msg.Method != "initialize" {
+		return nil, &Error{Code: CodeServerNotInitialized, Message: "server is not initialized"}
+	}
+
+	if s.shutdown {
+		return nil, &Error{Code: CodeInvalidRequest, Message: "server is shutting down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		s.initialized = true
+		return &InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync:       SyncIncremental,
+				DocumentSymbolProvider: true,
+				SelectionRangeProvider: true,
+				FoldingRangeProvider:   true,
+			},
+			ServerInfo: ServerInfo{Name: "motion"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+
+		doc := params.TextDocument
+		s.docs[doc.URI] = newDocument(doc.URI, doc.Version, doc.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+
+		doc, err := s.document(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, change := range params.ContentChanges {
+			if err := doc.applyChange(change); err != nil {
+				// the text doesn't match the client's anymore, the document
+				// has to be opened again
+				delete(s.docs, doc.uri)
+				return nil, fmt.Errorf("document %q is closed: %w", doc.uri, err)
+			}
+		}
+		doc.version = params.TextDocument.Version
+		return nil, nil
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+
+		delete(s.docs, params.TextDocument.URI)
+		return nil, nil
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+
+		doc, p, err := s.parse(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+
+		return documentSymbols(doc, p), nil
+	case "textDocument/selectionRange":
+		var params SelectionRangeParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+
+		doc, p, err := s.parse(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+
+		ranges := make([]SelectionRange, 0, len(params.Positions))
+		for _, pos := range params.Positions {
+			offset, err := doc.offset(pos)
+			if err != nil {
+				return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+			}
+			ranges = append(ranges, selectionRange(doc, p, offset))
+		}
+		return ranges, nil
+	case "textDocument/foldingRange":
+		var params FoldingRangeParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+
+		_, p, err := s.parse(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+
+		return foldingRanges(p)
+	case "motion/enclosing", "motion/next", "motion/prev":
+		var params MotionParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+
+		doc, p, err := s.parse(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+
+		offset, err := doc.offset(params.Position)
+		if err != nil {
+			return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+		}
+
+		return p.Run(&astcontext.Query{
+			Mode:   msg.Method[len("motion/"):],
+			Offset: offset,
+			Shift:  params.Shift,
+		})
+	}
+
+	return nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("method %q not found", msg.Method)}
+}
+
+// logError sends the error of the given notification to the client with the
+// "window/logMessage" notification
+func (s *Server) logError(method string, cause error) error {
+	params, err := json.Marshal(&LogMessageParams{
+		Type:    MessageError,
+		Message: fmt.Sprintf("%s: %s", method, cause),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.conn.write(&message{Method: "window/logMessage", Params: params})
+}
+
+// document returns the opened document with the given URI
+func (s *Server) document(uri string) (*document, error) {
+	doc, ok := s.docs[uri]
+	if !ok {
+		return nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("document %q is not opened", uri)}
+	}
+	return doc, nil
+}
+
+// parse returns the opened document with the given URI and its parser
+func (s *Server) parse(uri string) (*document, *astcontext.Parser, error) {
+	doc, err := s.document(uri)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p, err := doc.parse()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return doc, p, nil
+}
+
+func unmarshalParams(msg *message, v interface{}) error {
+	if err := json.Unmarshal(msg.Params, v); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// nullID returns the id of responses to messages which couldn't be decoded
+func nullID() *json.RawMessage {
+	id := json.RawMessage("null")
+	return &id
+}
+
+// documentSymbols returns the top level declarations of the document.
+// Methods are children of their receiver type, if it's declared in the same
+// document.
+func documentSymbols(doc *document, p *astcontext.Parser) []DocumentSymbol {
+	fset := p.FileSet()
+	symbols := []DocumentSymbol{}
+	types := make(map[string]int) // type name to the index in symbols
+
+	var methods []*ast.FuncDecl
+	for _, decl := range p.File().Decls {
+		switch x := decl.(type) {
+		case *ast.FuncDecl:
+			if x.Recv != nil {
+				methods = append(methods, x)
+				continue
+			}
+
+			symbols = append(symbols, funcSymbol(doc, fset, x))
+		case *ast.GenDecl:
+			for _, spec := range x.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					kind := SymbolClass
+					switch spec.Type.(type) {
+					case *ast.StructType:
+						kind = SymbolStruct
+					case *ast.InterfaceType:
+						kind = SymbolInterface
+					}
+
+					types[spec.Name.Name] = len(symbols)
+					symbols = append(symbols, DocumentSymbol{
+						Name:           spec.Name.Name,
+						Detail:         astcontext.NewTypeSignature(spec).Type,
+						Kind:           kind,
+						Range:          doc.nodeRange(fset, spec.Pos(), spec.End()),
+						SelectionRange: doc.nodeRange(fset, spec.Name.Pos(), spec.Name.End()),
+					})
+				case *ast.ValueSpec:
+					kind := SymbolVariable
+					if x.Tok == token.CONST {
+						kind = SymbolConstant
+					}
+
+					for _, name := range spec.Names {
+						symbols = append(symbols, DocumentSymbol{
+							Name:           name.Name,
+							Kind:           kind,
+							Range:          doc.nodeRange(fset, spec.Pos(), spec.End()),
+							SelectionRange: doc.nodeRange(fset, name.Pos(), name.End()),
+						})
+					}
+				}
+			}
+		}
+	}
+
+	for _, method := range methods {
+		sym := funcSymbol(doc, fset, method)
+		sym.Kind = SymbolMethod
+
+		i, ok := types[receiverName(method)]
+		if !ok {
+			symbols = append(symbols, sym)
+			continue
+		}
+		symbols[i].Children = append(symbols[i].Children, sym)
+	}
+
+	return symbols
+}
+
+func funcSymbol(doc *document, fset *token.FileSet, fn *ast.FuncDecl) DocumentSymbol {
+	return DocumentSymbol{
+		Name:           fn.Name.Name,
+		Detail:         astcontext.NewFuncSignature(fn).Full,
+		Kind:           SymbolFunction,
+		Range:          doc.nodeRange(fset, fn.Pos(), fn.End()),
+		SelectionRange: doc.nodeRange(fset, fn.Name.Pos(), fn.Name.End()),
+	}
+}
+
+// receiverName returns the type name of the method receiver, i.e: "T" for
+// "func (t *T[K]) foo()"
+func receiverName(fn *ast.FuncDecl) string {
+	if len(fn.Recv.List) == 0 {
+		return ""
+	}
+
+	expr := fn.Recv.List[0].Type
+	for {
+		switch x := expr.(type) {
+		case *ast.StarExpr:
+			expr = x.X
+		case *ast.IndexExpr:
+			expr = x.X
+		case *ast.IndexListExpr:
+			expr = x.X
+		case *ast.ParenExpr:
+			expr = x.X
+		case *ast.Ident:
+			return x.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// selectionRange returns the ranges of all nodes containing the offset, from
+// the innermost node up to the whole document.
+func selectionRange(doc *document, p *astcontext.Parser, offset int) SelectionRange {
+	fset := p.FileSet()
+	file := p.File()
+	pos := fset.File(file.Pos()).Pos(offset)
+
+	sel := &SelectionRange{
+		Range: Range{End: doc.position(len(doc.text))},
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil || !(n.Pos() <= pos && pos < n.End()) {
+			return false
+		}
+
+		r := doc.nodeRange(fset, n.Pos(), n.End())
+		if r != sel.Range {
+			sel = &SelectionRange{Range: r, Parent: sel}
+		}
+		return true
+	})
+
+	return *sel
+}
+
+// foldingRanges returns the folds of the document
+func foldingRanges(p *astcontext.Parser) ([]FoldingRange, error) {
+	folds, err := p.Folds()
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := make([]FoldingRange, 0, len(folds))
+	for _, fold := range folds {
+		r := FoldingRange{
+			StartLine: fold.StartLine - 1,
+			EndLine:   fold.EndLine - 1,
+		}
+
+		switch fold.Kind {
+		case "comment", "imports":
+			r.Kind = fold.Kind
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	return ranges, nil
+}
@@ -0,0 +1,344 @@
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/fatih/motion/astcontext"
+)
+
+// client is an in-process LSP client talking to a Server over pipes
+type client struct {
+	t    *testing.T
+	conn *conn
+	id   int
+	done chan error
+}
+
+func newClient(t *testing.T) *client {
+	serverR, clientW := io.Pipe()
+	clientR, serverW := io.Pipe()
+
+	c := &client{
+		t:    t,
+		conn: newConn(clientR, clientW),
+		done: make(chan error, 1),
+	}
+
+	go func() {
+		err := NewServer().Serve(serverR, serverW)
+		serverW.Close()
+		c.done <- err
+	}()
+
+	t.Cleanup(func() { clientW.Close() })
+	return c
+}
+
+// call sends a request and decodes the result into result. It returns the
+// error of the response, if any.
+func (c *client) call(method string, params, result interface{}) *Error {
+	c.t.Helper()
+
+	c.id++
+	id := json.RawMessage(strconv.Itoa(c.id))
+	c.send(&message{ID: &id, Method: method}, params)
+
+	resp, err := c.conn.read()
+	if err != nil {
+		c.t.Fatalf("%s: couldn't read response: %s", method, err)
+	}
+
+	if string(*resp.ID) != string(id) {
+		c.t.Fatalf("%s: wrong response id, want: %s, got: %s", method, id, *resp.ID)
+	}
+
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			c.t.Fatalf("%s: couldn't decode result: %s", method, err)
+		}
+	}
+	return nil
+}
+
+// notify sends a notification
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+	c.send(&message{Method: method}, params)
+}
+
+func (c *client) send(msg *message, params interface{}) {
+	c.t.Helper()
+
+	if params != nil {
+		var err error
+		msg.Params, err = json.Marshal(params)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+	}
+
+	if err := c.conn.write(msg); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+const testURI = "file:///tmp/server.go"
+
+var testSrc = `package server
+
+import "fmt"
+
+// Handler handles
+// requests
+type Handler struct{}
+
+func (h *Handler) ServeHTTP() {
+	fmt.Println("ü")
+}
+
+func main() {
+	_ = func() {
+		println("x")
+	}
+}
+`
+
+func initClient(t *testing.T) *client {
+	c := newClient(t)
+
+	var init InitializeResult
+	if err := c.call("initialize", &InitializeParams{}, &init); err != nil {
+		t.Fatal(err)
+	}
+
+	if !init.Capabilities.DocumentSymbolProvider {
+		t.Fatal("document symbols should be supported")
+	}
+
+	c.notify("initialized", struct{}{})
+	c.notify("textDocument/didOpen", &DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: testURI, LanguageID: "go", Version: 1, Text: testSrc},
+	})
+	return c
+}
+
+func TestServer_Lifecycle(t *testing.T) {
+	c := newClient(t)
+
+	if err := c.call("textDocument/documentSymbol", &DocumentSymbolParams{}, nil); err == nil || err.Code != CodeServerNotInitialized {
+		t.Fatalf("request before initialize should fail, got: %v", err)
+	}
+
+	if err := c.call("initialize", &InitializeParams{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.call("unknown/method", struct{}{}, nil); err == nil || err.Code != CodeMethodNotFound {
+		t.Fatalf("unknown method should fail, got: %v", err)
+	}
+
+	if err := c.call("shutdown", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		t.Fatalf("server should exit cleanly, got: %s", err)
+	}
+}
+
+func TestServer_DocumentSymbol(t *testing.T) {
+	c := initClient(t)
+
+	var symbols []DocumentSymbol
+	if err := c.call("textDocument/documentSymbol", &DocumentSymbolParams{
+		TextDocument: TextDocumentIdentifier{URI: testURI},
+	}, &symbols); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []DocumentSymbol{
+		{
+			Name:           "Handler",
+			Detail:         "struct{}",
+			Kind:           SymbolStruct,
+			Range:          Range{Start: Position{6, 5}, End: Position{6, 21}},
+			SelectionRange: Range{Start: Position{6, 5}, End: Position{6, 12}},
+			Children: []DocumentSymbol{
+				{
+					Name:           "ServeHTTP",
+					Detail:         "func (h *Handler) ServeHTTP()",
+					Kind:           SymbolMethod,
+					Range:          Range{Start: Position{8, 0}, End: Position{10, 1}},
+					SelectionRange: Range{Start: Position{8, 18}, End: Position{8, 27}},
+				},
+			},
+		},
+		{
+			Name:           "main",
+			Detail:         "func main()",
+			Kind:           SymbolFunction,
+			Range:          Range{Start: Position{12, 0}, End: Position{16, 1}},
+			SelectionRange: Range{Start: Position{12, 5}, End: Position{12, 9}},
+		},
+	}
+
+	if !reflect.DeepEqual(symbols, want) {
+		t.Errorf("wrong symbols:\nwant: %+v\ngot:  %+v", want, symbols)
+	}
+}
+
+func TestServer_SelectionRange(t *testing.T) {
+	c := initClient(t)
+
+	var ranges []SelectionRange
+	if err := c.call("textDocument/selectionRange", &SelectionRangeParams{
+		TextDocument: TextDocumentIdentifier{URI: testURI},
+		Positions:    []Position{{Line: 14, Character: 11}},
+	}, &ranges); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ranges) != 1 {
+		t.Fatalf("want 1 selection range, got: %d", len(ranges))
+	}
+
+	// innermost is the "x" string literal
+	sel := &ranges[0]
+	if want := (Range{Start: Position{14, 10}, End: Position{14, 13}}); sel.Range != want {
+		t.Errorf("wrong innermost range, want: %v, got: %v", want, sel.Range)
+	}
+
+	// every parent should contain its child
+	for ; sel.Parent != nil; sel = sel.Parent {
+		p := sel.Parent.Range
+		if before(sel.Range.Start, p.Start) || before(p.End, sel.Range.End) {
+			t.Errorf("parent %v doesn't contain %v", p, sel.Range)
+		}
+	}
+
+	if want := (Range{End: Position{17, 0}}); sel.Range != want {
+		t.Errorf("outermost range should be the whole document, got: %v", sel.Range)
+	}
+}
+
+func TestServer_FoldingRange(t *testing.T) {
+	c := initClient(t)
+
+	var folds []FoldingRange
+	if err := c.call("textDocument/foldingRange", &FoldingRangeParams{
+		TextDocument: TextDocumentIdentifier{URI: testURI},
+	}, &folds); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []FoldingRange{
+		{StartLine: 4, EndLine: 5, Kind: "comment"},
+		{StartLine: 8, EndLine: 10},
+		{StartLine: 12, EndLine: 16},
+		{StartLine: 13, EndLine: 15},
+	}
+
+	if !reflect.DeepEqual(folds, want) {
+		t.Errorf("wrong folds:\nwant: %+v\ngot:  %+v", want, folds)
+	}
+}
+
+func TestServer_Motion(t *testing.T) {
+	c := initClient(t)
+
+	var res astcontext.Result
+	if err := c.call("motion/enclosing", &MotionParams{
+		TextDocument: TextDocumentIdentifier{URI: testURI},
+		Position:     Position{Line: 9, Character: 15},
+	}, &res); err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Func.Signature.Name != "ServeHTTP" {
+		t.Errorf("wrong enclosing function: %s", res.Func.Signature.Name)
+	}
+
+	if res.Func.FuncPos.Filename != "/tmp/server.go" {
+		t.Errorf("wrong filename: %s", res.Func.FuncPos.Filename)
+	}
+
+	// rename the method with an incremental change and query again
+	c.notify("textDocument/didChange", &DidChangeTextDocumentParams{
+		TextDocument: VersionedTextDocumentIdentifier{URI: testURI, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{
+			{
+				Range: &Range{Start: Position{8, 18}, End: Position{8, 27}},
+				Text:  "Serve",
+			},
+		},
+	})
+
+	res = astcontext.Result{}
+	if err := c.call("motion/prev", &MotionParams{
+		TextDocument: TextDocumentIdentifier{URI: testURI},
+		Position:     Position{Line: 12, Character: 0},
+	}, &res); err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Func.Signature.Name != "Serve" {
+		t.Errorf("wrong previous function: %s", res.Func.Signature.Name)
+	}
+
+	if err := c.call("motion/next", &MotionParams{
+		TextDocument: TextDocumentIdentifier{URI: testURI},
+		Position:     Position{Line: 14, Character: 0},
+	}, nil); err == nil || err.Code != CodeRequestFailed ||
+		err.Data == nil || err.Data.Code != astcontext.CodeNoFunc {
+		t.Errorf("next function after the last one should fail, got: %+v", err)
+	}
+}
+
+func before(a, b Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Character < b.Character
+}
+
+func TestServer_FailedChange(t *testing.T) {
+	c := initClient(t)
+
+	c.notify("textDocument/didChange", &DidChangeTextDocumentParams{
+		TextDocument: VersionedTextDocumentIdentifier{URI: testURI, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{
+			{
+				Range: &Range{Start: Position{100, 0}, End: Position{100, 1}},
+				Text:  "x",
+			},
+		},
+	})
+
+	msg, err := c.conn.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var params LogMessageParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Method != "window/logMessage" || msg.ID != nil || params.Type != MessageError {
+		t.Fatalf("failed change should be logged, got: %s %s", msg.Method, msg.Params)
+	}
+
+	// the document is out of sync, it's closed instead of answering with
+	// the stale text
+	if err := c.call("textDocument/documentSymbol", &DocumentSymbolParams{
+		TextDocument: TextDocumentIdentifier{URI: testURI},
+	}, nil); err == nil || err.Code != CodeInvalidParams {
+		t.Errorf("document should be closed after a failed change, got: %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+// Package lua provides an encoder for structured data as a Lua table
+// constructor. The output is an expression, to load it with Neovim's
+// loadstring prefix it with "return ", i.e:
+//
+//	local result = loadstring("return " .. output)()
+//
+// Structs and maps are encoded as tables with string keys, arrays and slices
+// as sequences and nil values as nil.
+//
+// Struct fields are named by the "lua" struct tag. If a field has no "lua"
+// tag, the "json" tag is used. The tag format is the same as of the
+// encoding/json package, i.e: `lua:"name,omitempty"`.
+package lua
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/fatih/motion/internal/tags"
+)
+
+// Marshal returns the Lua encoding of v.
+func Marshal(x interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := marshal(&buf, reflect.ValueOf(x)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func marshal(buf *bytes.Buffer, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Invalid:
+		buf.WriteString("nil")
+
+	case reflect.Bool:
+		fmt.Fprint(buf, v.Bool())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr:
+		fmt.Fprint(buf, v.Interface())
+
+	case reflect.Float32, reflect.Float64:
+		writeFloat(buf, v.Float())
+
+	case reflect.String:
+		writeString(buf, v.String())
+
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("nil")
+			return nil
+		}
+		return marshal(buf, v.Elem())
+
+	case reflect.Array, reflect.Slice:
+		buf.WriteByte('{')
+		n := v.Len()
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			if err := marshal(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("non-string key type in %s", v.Type())
+		}
+
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			writeKey(buf, k.String())
+			if err := marshal(buf, v.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+
+	case reflect.Struct:
+		buf.WriteByte('{')
+		sep := ""
+		for _, f := range tags.Fields(v.Type(), "lua", "json") {
+			fv, ok := f.Value(v)
+			if !ok {
+				continue
+			}
+
+			buf.WriteString(sep)
+			sep = ", "
+
+			writeKey(buf, f.Name)
+			if err := marshal(buf, fv); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+
+	case reflect.Complex64, reflect.Complex128,
+		reflect.UnsafePointer,
+		reflect.Func,
+		reflect.Chan:
+		return fmt.Errorf("unsupported type: %s", v.Type())
+	}
+
+	return nil
+}
+
+// keywords are the reserved words of Lua, they can't be used as names
+var keywords = map[string]bool{
+	"and": true, "break": true, "do": true, "else": true, "elseif": true,
+	"end": true, "false": true, "for": true, "function": true, "goto": true,
+	"if": true, "in": true, "local": true, "nil": true, "not": true, "or": true,
+	"repeat": true, "return": true, "then": true, "true": true, "until": true,
+	"while": true,
+}
+
+// writeKey writes the key of a table field followed by the equal sign. Keys
+// which are not valid Lua names are written as ["key"].
+func writeKey(buf *bytes.Buffer, key string) {
+	if isName(key) {
+		buf.WriteString(key)
+	} else {
+		buf.WriteByte('[')
+		writeString(buf, key)
+		buf.WriteByte(']')
+	}
+	buf.WriteString(" = ")
+}
+
+// isName reports whether s is a valid Lua name, which can be used as a table
+// key without brackets.
+func isName(s string) bool {
+	if s == "" || keywords[s] {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// writeString writes s as a double quoted string. Lua strings are byte
+// strings, non-ASCII bytes are written as is and control characters are
+// written as decimal escapes.
+func writeString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case c == '\n':
+			buf.WriteString(`\n`)
+		case c == '\t':
+			buf.WriteString(`\t`)
+		case c == '\r':
+			buf.WriteString(`\r`)
+		case c < 0x20 || c == 0x7f:
+			// always use three digits, so a following digit is not part of
+			// the escape
+			fmt.Fprintf(buf, `\%03d`, c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// writeFloat writes f as a Lua number expression
+func writeFloat(buf *bytes.Buffer, f float64) {
+	switch {
+	case math.IsNaN(f):
+		buf.WriteString("(0/0)")
+	case math.IsInf(f, 1):
+		buf.WriteString("math.huge")
+	case math.IsInf(f, -1):
+		buf.WriteString("-math.huge")
+	default:
+		buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	}
+}
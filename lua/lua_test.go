@@ -0,0 +1,55 @@
+package lua
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	type pos struct {
+		Line int    `json:"line"`
+		Col  int    `lua:"column" json:"col"`
+		File string `json:"file,omitempty"`
+		End  int    `json:"end"`
+		Skip bool   `json:"-"`
+	}
+
+	type message struct {
+		pos
+		Text string `json:"text"`
+	}
+
+	cases := []struct {
+		in   interface{}
+		want string
+	}{
+		{nil, `nil`},
+		{true, `true`},
+		{42, `42`},
+		{1.5, `1.5`},
+		{math.Inf(-1), `-math.huge`},
+		{"a \"b\" \\ ü\n\x011", `"a \"b\" \\ ü\n\0011"`},
+		{[]int{1, 2}, `{1, 2}`},
+		{map[string]int{"b": 2, "a": 1}, `{a = 1, b = 2}`},
+		{map[string]int{"with space": 1, "1a": 2}, `{["1a"] = 2, ["with space"] = 1}`},
+		{pos{Line: 1, Col: 2}, `{line = 1, column = 2, ["end"] = 0}`},
+		{&pos{File: "a.go"}, `{line = 0, column = 0, file = "a.go", ["end"] = 0}`},
+		{message{pos{Line: 1}, "a"}, `{line = 1, column = 0, ["end"] = 0, text = "a"}`},
+		{(*pos)(nil), `nil`},
+	}
+
+	for _, tc := range cases {
+		out, err := Marshal(tc.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(out) != tc.want {
+			t.Errorf("Marshal(%#v)\nwant: %s\ngot:  %s", tc.in, tc.want, out)
+		}
+	}
+
+	if _, err := Marshal(make(chan int)); err == nil {
+		t.Error("channels should not be supported")
+	}
+}
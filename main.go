@@ -5,81 +5,232 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"runtime/debug"
 	"strings"
 
 	"github.com/fatih/motion/astcontext"
+	"github.com/fatih/motion/elisp"
+	"github.com/fatih/motion/lsp"
+	"github.com/fatih/motion/lua"
 	"github.com/fatih/motion/vim"
 )
 
+// Exit codes of motion. Failed queries are written to stdout as a result, so
+// the editor can parse them, other errors are written to stderr. The source
+// and query errors only have their own exit codes with -exit-codes, which
+// writes source errors to stdout as well.
+const (
+	exitOK         = 0
+	exitError      = 1 // invalid usage or internal error
+	exitParseError = 2 // the source couldn't be parsed
+	exitQueryError = 3 // at least one of the queries failed
+	exitReadError  = 4 // the source couldn't be read
+)
+
 func main() {
-	if err := realMain(); err != nil {
+	code, err := realMain()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
-		os.Exit(1)
+		os.Exit(exitError)
 	}
+	os.Exit(code)
 }
 
-func realMain() error {
+func realMain() (int, error) {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "lsp":
+			return exitOK, lsp.NewServer().Serve(os.Stdin, os.Stdout)
+		case "version":
+			fmt.Printf("motion %s (schema version %d)\n", version(), astcontext.SchemaVersion)
+			return exitOK, nil
+		}
+	}
+
 	var (
 		flagFile   = flag.String("file", "", "Filename to be parsed")
 		flagDir    = flag.String("dir", "", "Directory to be parsed")
 		flagOffset = flag.Int("offset", 0, "Byte offset of the cursor position")
 		flagMode   = flag.String("mode", "",
-			"Running mode. One of {enclosing, next, prev, decls, comment}")
+			"Running mode. One of {enclosing, next, prev, decls, comment, imports, context, symbols, folds, todos, "+
+				"directives, reflowdoc, docstub, docstubs, metrics, returns, nextreturn, prevreturn}")
 		flagInclude = flag.String("include", "",
 			"Included declarations for mode {decls}. Comma delimited. Options: {func, type}")
-		flagShift         = flag.Int("shift", 0, "Shift value for the modes {next, prev}")
-		flagFormat        = flag.String("format", "json", "Output format. One of {json, vim}")
+		flagShift  = flag.Int("shift", 0, "Shift value for the modes {next, prev, nextreturn, prevreturn}")
+		flagFormat = flag.String("format", "json",
+			"Output format. One of {json, vim, elisp, lua, text}")
 		flagParseComments = flag.Bool("parse-comments", false,
 			"Parse comments and add them to AST")
+		flagBuild = flag.Bool("build", false,
+			"Honor build constraints when parsing -dir. Implied by {tags, goos, goarch, tests, xtests}")
+		flagTags   = flag.String("tags", "", "Comma delimited build tags for -dir")
+		flagGOOS   = flag.String("goos", "", "GOOS used for build constraints of -dir")
+		flagGOARCH = flag.String("goarch", "", "GOARCH used for build constraints of -dir")
+		flagTests  = flag.Bool("tests", false, "Include _test.go files of the package for -dir")
+		flagXTests = flag.Bool("xtests", false, "Include the external test package for -dir")
+		flagCache  = flag.String("cache", "",
+			"Directory to cache the declarations of -dir in. Unchanged files are not parsed again")
+		flagSymbol = flag.String("symbol", "", "Symbol to search for in mode {symbols}")
+		flagSkip   = flag.String("skip", "",
+			"Comma delimited .gitignore like patterns of paths to skip in modes {symbols, todos}")
+		flagMarkers = flag.String("markers", "",
+			"Comma delimited markers for mode {todos}. Default: TODO,FIXME,XXX,NOTE,BUG")
+		flagRecursive = flag.Bool("recursive", false, "Walk -dir recursively in mode {todos}")
+		flagWidth     = flag.Int("width", 0, "Maximum line width for mode {reflowdoc}. Default: 80")
+		flagMetrics   = flag.Bool("metrics", false, "Include the metrics of functions for modes {enclosing, next, prev}")
+		flagPage      = flag.Int("page", 0, "Zero based page index for mode {symbols}")
+		flagLimit     = flag.Int("limit", 0, "Number of results per page for mode {symbols}")
+		flagQueries   = flag.String("queries", "",
+			"JSON array of queries to run against a single parse. Use \"-\" to read from stdin")
+		flagSchema = flag.Bool("schema", false,
+			"Print the JSON Schema of the output. Property names follow -format")
+		flagExitCodes = flag.Bool("exit-codes", false,
+			"Write source errors to stdout in -format and exit with 2 for parse errors, 3 for failed queries and 4 for read errors")
 	)
 
 	flag.Parse()
 	if flag.NFlag() == 0 {
 		flag.Usage()
-		return nil
+		return exitOK, nil
+	}
+
+	if *flagSchema {
+		// elisp and lua use the json tags
+		tag := "json"
+		if *flagFormat == "vim" {
+			tag = "vim"
+		}
+
+		b, err := json.MarshalIndent(astcontext.Schema(tag), "", "\t")
+		if err != nil {
+			return exitError, err
+		}
+		fmt.Printf("%s\n", b)
+		return exitOK, nil
+	}
+
+	var queries []*astcontext.Query
+	if *flagQueries != "" {
+		var err error
+		queries, err = readQueries(*flagQueries)
+		if err != nil {
+			return exitError, err
+		}
+
+		for _, query := range queries {
+			if needsComments(query.Mode) {
+				*flagParseComments = true
+			}
+		}
+	} else {
+		if *flagMode == "" {
+			return exitError, errors.New("no mode is passed")
+		}
+
+		if needsComments(*flagMode) {
+			*flagParseComments = true
+		}
 	}
 
-	if *flagMode == "" {
-		return errors.New("no mode is passed")
+	var skip []string
+	if *flagSkip != "" {
+		skip = strings.Split(*flagSkip, ",")
 	}
 
-	if *flagMode == "comment" {
-		*flagParseComments = true
+	var markers []string
+	if *flagMarkers != "" {
+		markers = strings.Split(*flagMarkers, ",")
 	}
 
 	opts := &astcontext.ParserOptions{
 		Comments: *flagParseComments,
 		File:     *flagFile,
 		Dir:      *flagDir,
+		CacheDir: *flagCache,
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "build", "tags", "goos", "goarch", "tests", "xtests":
+			*flagBuild = true
+		}
+	})
+
+	if *flagBuild {
+		opts.Build = &astcontext.BuildOptions{
+			GOOS:   *flagGOOS,
+			GOARCH: *flagGOARCH,
+			Tests:  *flagTests,
+			XTests: *flagXTests,
+		}
+
+		if *flagTags != "" {
+			opts.Build.Tags = strings.Split(*flagTags, ",")
+		}
 	}
 
 	parser, err := astcontext.NewParser(opts)
 	if err != nil {
-		return err
-	}
+		if !*flagExitCodes {
+			return exitError, err
+		}
 
-	query := &astcontext.Query{
-		Mode:     *flagMode,
-		Offset:   *flagOffset,
-		Shift:    *flagShift,
-		Includes: strings.Split(*flagInclude, ","),
+		code := exitParseError
+		if errors.Is(err, astcontext.ErrReadSource) {
+			code = exitReadError
+		}
+		return code, output(errorResult(err), *flagFormat, *flagFile)
 	}
 
-	result, err := parser.Run(query)
-
+	code := exitOK
 	var res interface{}
+	if queries != nil {
+		results := make([]interface{}, len(queries))
+		for i, query := range queries {
+			var ok bool
+			results[i], ok = runQuery(parser, query)
+			if !ok {
+				code = exitQueryError
+			}
+		}
+		res = results
+	} else {
+		var ok bool
+		res, ok = runQuery(parser, &astcontext.Query{
+			Mode:     *flagMode,
+			Offset:   *flagOffset,
+			Shift:    *flagShift,
+			Includes: strings.Split(*flagInclude, ","),
+			Markers:  markers,
+			Width:    *flagWidth,
+			Metrics:  *flagMetrics,
 
-	res = result
-	if err != nil {
-		res = struct {
-			Err string `json:"err" vim:"err"`
-		}{
-			Err: err.Error(),
+			Symbol:    *flagSymbol,
+			Page:      *flagPage,
+			Limit:     *flagLimit,
+			Skip:      skip,
+			Recursive: *flagRecursive,
+		})
+		if !ok {
+			code = exitQueryError
 		}
 	}
 
-	switch *flagFormat {
+	// failed queries are results as well, unless the editor asks for the
+	// exit code
+	if !*flagExitCodes {
+		code = exitOK
+	}
+
+	return code, output(res, *flagFormat, *flagFile)
+}
+
+// output writes the given result to stdout in the given format. The filename
+// is used by the text formats for the items without a filename.
+func output(res interface{}, format, filename string) error {
+	switch format {
 	case "json":
 		b, err := json.MarshalIndent(&res, "", "\t")
 		if err != nil {
@@ -92,9 +243,110 @@ func realMain() error {
 			return fmt.Errorf("VIM error: %s", err)
 		}
 		os.Stdout.Write(b)
+	case "elisp":
+		b, err := elisp.Marshal(&res)
+		if err != nil {
+			return fmt.Errorf("elisp error: %s", err)
+		}
+		os.Stdout.Write(b)
+	case "lua":
+		b, err := lua.Marshal(&res)
+		if err != nil {
+			return fmt.Errorf("lua error: %s", err)
+		}
+		os.Stdout.Write(b)
+	case "text":
+		writeLocations(res, filename)
 	default:
-		return fmt.Errorf("wrong -format value: %q", *flagFormat)
+		return fmt.Errorf("wrong -format value: %q", format)
 	}
 
 	return nil
 }
+
+// writeLocations writes the items of the given result to stdout, one per line
+// in the GNU "file:line:col: text" format. Errors without a position are
+// written to stderr.
+func writeLocations(res interface{}, filename string) {
+	var locs []astcontext.Location
+	switch r := res.(type) {
+	case []interface{}:
+		for _, x := range r {
+			writeLocations(x, filename)
+		}
+	case *astcontext.Result:
+		locs = r.Locations(filename)
+	case *astcontext.ErrorResult:
+		locs = r.Locations()
+		if len(locs) == 0 {
+			fmt.Fprintf(os.Stderr, "%s\n", r.Err)
+		}
+	}
+
+	for _, l := range locs {
+		fmt.Println(l)
+	}
+}
+
+// needsComments reports whether the given mode needs the comments to be
+// parsed
+func needsComments(mode string) bool {
+	switch mode {
+	case "comment", "folds", "todos", "directives", "reflowdoc", "docstub", "docstubs":
+		return true
+	}
+	return false
+}
+
+// runQuery runs the given query and returns either the result or the error
+// wrapped in a struct, so the editor can parse it. The boolean is false if
+// the query failed.
+func runQuery(parser *astcontext.Parser, query *astcontext.Query) (interface{}, bool) {
+	result, err := parser.Run(query)
+	if err != nil {
+		return errorResult(err), false
+	}
+	return result, true
+}
+
+// errorResult wraps the error so the editor can parse it
+func errorResult(err error) interface{} {
+	return astcontext.NewErrorResult(err)
+}
+
+// version returns the version of the motion module, which is set if motion is
+// installed with "go install"
+func version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "" {
+		return "(devel)"
+	}
+	return info.Main.Version
+}
+
+// readQueries decodes an array of queries from the given value. The array can
+// be either JSON or a Vimscript List literal, such as the output of Vim's
+// string() function. If the value is "-", the queries are read from stdin.
+func readQueries(value string) ([]*astcontext.Query, error) {
+	data := []byte(value)
+	if value == "-" {
+		var err error
+		data, err = io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read queries: %s", err)
+		}
+	}
+
+	var queries []*astcontext.Query
+	if err := json.Unmarshal(data, &queries); err != nil {
+		if verr := vim.Unmarshal(data, &queries); verr != nil {
+			return nil, fmt.Errorf("couldn't decode queries: %s", err)
+		}
+	}
+
+	if len(queries) == 0 {
+		return nil, errors.New("no queries are passed")
+	}
+
+	return queries, nil
+}
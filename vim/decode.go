@@ -0,0 +1,760 @@
+package vim
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/motion/internal/tags"
+)
+
+// Unmarshaler is the interface implemented by types that can unmarshal a
+// Vimscript literal of themselves.
+type Unmarshaler interface {
+	UnmarshalVim([]byte) error
+}
+
+var (
+	unmarshalerType     = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// SyntaxError describes an invalid Vimscript literal
+type SyntaxError struct {
+	msg    string
+	Offset int // offset of the error in the input, in bytes
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at offset %d", e.msg, e.Offset)
+}
+
+// UnmarshalTypeError describes a Vimscript value which can't be stored in a Go
+// value of a specific type
+type UnmarshalTypeError struct {
+	Value  string       // description of the Vimscript value, i.e: "list"
+	Type   reflect.Type // type of the Go value it could not be assigned to
+	Offset int          // offset of the value in the input, in bytes
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	return fmt.Sprintf("cannot unmarshal %s into Go value of type %s at offset %d",
+		e.Value, e.Type, e.Offset)
+}
+
+// Unmarshal parses the Vimscript literal in data and stores the result in the
+// value pointed to by v. The literal can be a Dictionary, a List, a String, a
+// Number, a Float or one of the special values v:true, v:false, v:null and
+// v:none, such as the output of Vim's string() function.
+//
+// Dictionaries are decoded into structs according to the "vim" struct tags,
+// the same way as Marshal encodes them. Unknown keys are ignored. Decoding
+// into an empty interface stores Dictionaries as map[string]interface{},
+// Lists as []interface{}, Numbers as int64 and Floats as float64. Numbers can
+// be decoded into booleans as Vim uses them as booleans, zero being false.
+func Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("vim: Unmarshal(non-pointer %T)", v)
+	}
+
+	p := &parser{data: string(data)}
+	n, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+
+	p.skipSpace()
+	if p.pos != len(p.data) {
+		return p.errorf("invalid character %q after top-level value", p.data[p.pos])
+	}
+
+	return decode(n, rv.Elem())
+}
+
+type nodeKind int
+
+const (
+	nodeNull nodeKind = iota
+	nodeBool
+	nodeNumber
+	nodeFloat
+	nodeString
+	nodeList
+	nodeDict
+)
+
+func (k nodeKind) String() string {
+	return [...]string{"null", "boolean", "number", "float", "string", "list", "dictionary"}[k]
+}
+
+// node is a parsed Vimscript value
+type node struct {
+	kind   nodeKind
+	offset int
+	raw    string // literal source of the value
+
+	b     bool
+	n     int64
+	f     float64
+	s     string
+	list  []*node
+	keys  []string
+	items []*node
+}
+
+// maxDepth is the maximum nesting depth of Lists and Dictionaries, the same
+// as the limit of encoding/json. Deeper literals return a SyntaxError instead
+// of overflowing the stack.
+const maxDepth = 10000
+
+type parser struct {
+	data  string
+	pos   int
+	depth int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{msg: fmt.Sprintf(format, args...), Offset: p.pos}
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) parseValue() (*node, error) {
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, p.errorf("unexpected end of input")
+	}
+
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxDepth {
+		return nil, p.errorf("exceeded max depth of %d", maxDepth)
+	}
+
+	start := p.pos
+	n, err := p.parseValueKind()
+	if err != nil {
+		return nil, err
+	}
+
+	n.offset = start
+	n.raw = p.data[start:p.pos]
+	return n, nil
+}
+
+func (p *parser) parseValueKind() (*node, error) {
+	c := p.data[p.pos]
+	switch {
+	case c == '{':
+		return p.parseDict(false)
+	case c == '#' && strings.HasPrefix(p.data[p.pos:], "#{"):
+		p.pos++
+		return p.parseDict(true)
+	case c == '[':
+		return p.parseList()
+	case c == '\'' || c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: nodeString, s: s}, nil
+	case c == '-' || c == '+' || c == '.' || '0' <= c && c <= '9':
+		return p.parseNumber()
+	}
+
+	word := p.parseWord()
+	switch word {
+	case "v:true", "true":
+		return &node{kind: nodeBool, b: true}, nil
+	case "v:false", "false":
+		return &node{kind: nodeBool}, nil
+	case "v:null", "v:none", "null":
+		return &node{kind: nodeNull}, nil
+	case "inf", "nan":
+		p.pos -= len(word)
+		return p.parseNumber()
+	}
+
+	p.pos -= len(word)
+	return nil, p.errorf("invalid character %q looking for beginning of value", c)
+}
+
+// parseWord reads a keyword, i.e: "v:true"
+func (p *parser) parseWord() string {
+	start := p.pos
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if c == ':' || c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.data[start:p.pos]
+}
+
+func (p *parser) parseList() (*node, error) {
+	p.pos++ // [
+	n := &node{kind: nodeList}
+
+	for {
+		p.skipSpace()
+		if p.pos < len(p.data) && p.data[p.pos] == ']' {
+			p.pos++
+			return n, nil
+		}
+
+		item, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		n.list = append(n.list, item)
+
+		// a trailing comma is allowed
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, p.errorf("unexpected end of input, missing ']'")
+		}
+
+		switch p.data[p.pos] {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf("invalid character %q after list item, missing ','", p.data[p.pos])
+		}
+	}
+}
+
+// parseDict parses a Dictionary. If literal is true, keys are not quoted,
+// i.e: #{key: 1}.
+func (p *parser) parseDict(literal bool) (*node, error) {
+	p.pos++ // {
+	n := &node{kind: nodeDict}
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, p.errorf("unexpected end of input, missing '}'")
+		}
+
+		if p.data[p.pos] == '}' {
+			p.pos++
+			return n, nil
+		}
+
+		var key string
+		if literal {
+			start := p.pos
+			for p.pos < len(p.data) && isLiteralKeyChar(p.data[p.pos]) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("invalid character %q in literal dictionary key", p.data[p.pos])
+			}
+			key = p.data[start:p.pos]
+		} else {
+			k, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+
+			// Vim converts Number keys to strings
+			switch k.kind {
+			case nodeString:
+				key = k.s
+			case nodeNumber:
+				key = strconv.FormatInt(k.n, 10)
+			default:
+				p.pos = k.offset
+				return nil, p.errorf("invalid dictionary key of type %s", k.kind)
+			}
+		}
+
+		p.skipSpace()
+		if p.pos >= len(p.data) || p.data[p.pos] != ':' {
+			return nil, p.errorf("missing ':' after dictionary key")
+		}
+		p.pos++
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		n.keys = append(n.keys, key)
+		n.items = append(n.items, value)
+
+		// a trailing comma is allowed
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, p.errorf("unexpected end of input, missing '}'")
+		}
+
+		switch p.data[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+		default:
+			return nil, p.errorf("invalid character %q after dictionary value, missing ','", p.data[p.pos])
+		}
+	}
+}
+
+func isLiteralKeyChar(c byte) bool {
+	return c == '_' || c == '-' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// parseString parses a single or double quoted string according to ":help
+// literal-string" and ":help expr-quote".
+func (p *parser) parseString() (string, error) {
+	quote := p.data[p.pos]
+	p.pos++
+
+	var sb strings.Builder
+	if quote == '\'' {
+		for {
+			i := strings.IndexByte(p.data[p.pos:], '\'')
+			if i < 0 {
+				p.pos = len(p.data)
+				return "", p.errorf("unterminated string")
+			}
+
+			sb.WriteString(p.data[p.pos : p.pos+i])
+			p.pos += i + 1
+
+			// '' is a single quote
+			if p.pos < len(p.data) && p.data[p.pos] == '\'' {
+				sb.WriteByte('\'')
+				p.pos++
+				continue
+			}
+			return sb.String(), nil
+		}
+	}
+
+	for {
+		if p.pos >= len(p.data) {
+			return "", p.errorf("unterminated string")
+		}
+
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '"':
+			return sb.String(), nil
+		case '\\':
+		default:
+			sb.WriteByte(c)
+			continue
+		}
+
+		if p.pos >= len(p.data) {
+			return "", p.errorf("unterminated string")
+		}
+
+		c = p.data[p.pos]
+		p.pos++
+		switch c {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'e':
+			sb.WriteByte(0x1b)
+		case 'x', 'X', 'u', 'U':
+			max := map[byte]int{'x': 2, 'X': 2, 'u': 4, 'U': 8}[c]
+			start := p.pos
+			for p.pos < len(p.data) && p.pos-start < max && isHex(p.data[p.pos]) {
+				p.pos++
+			}
+
+			if start == p.pos {
+				// not followed by a hex digit, Vim keeps the character
+				sb.WriteByte(c)
+				break
+			}
+
+			v, _ := strconv.ParseUint(p.data[start:p.pos], 16, 32)
+			if c == 'x' || c == 'X' {
+				sb.WriteByte(byte(v))
+			} else if r := rune(v); utf8.ValidRune(r) {
+				sb.WriteRune(r)
+			} else {
+				sb.WriteRune(utf8.RuneError)
+			}
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			// octal, up to three digits
+			start := p.pos - 1
+			for p.pos < len(p.data) && p.pos-start < 3 && '0' <= p.data[p.pos] && p.data[p.pos] <= '7' {
+				p.pos++
+			}
+			v, _ := strconv.ParseUint(p.data[start:p.pos], 8, 16)
+			sb.WriteByte(byte(v))
+		case '<':
+			p.pos -= 2
+			return "", p.errorf("special key notation is not supported")
+		default:
+			// \\, \" and any other character is kept as is
+			sb.WriteByte(c)
+		}
+	}
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// parseNumber parses a Number or a Float. Numbers can be decimal,
+// hexadecimal (0x), binary (0b) or octal (0o or a leading zero). Floats
+// require a dot, as in Vimscript, i.e: 1.0 or 1.5e-3.
+func (p *parser) parseNumber() (*node, error) {
+	start := p.pos
+	neg := false
+	if c := p.data[p.pos]; c == '-' || c == '+' {
+		neg = c == '-'
+		p.pos++
+	}
+
+	rest := p.data[p.pos:]
+	switch {
+	case strings.HasPrefix(rest, "inf"):
+		p.pos += len("inf")
+		sign := 1
+		if neg {
+			sign = -1
+		}
+		return &node{kind: nodeFloat, f: math.Inf(sign)}, nil
+	case strings.HasPrefix(rest, "nan"):
+		p.pos += len("nan")
+		return &node{kind: nodeFloat, f: math.NaN()}, nil
+	}
+
+	digits := p.pos
+	for p.pos < len(p.data) && isNumberChar(p.data[p.pos]) {
+		p.pos++
+	}
+
+	// a float is digits, a dot, digits and an optional exponent
+	if p.pos+1 < len(p.data) && p.data[p.pos] == '.' && '0' <= p.data[p.pos+1] && p.data[p.pos+1] <= '9' && p.pos > digits {
+		p.pos++
+		for p.pos < len(p.data) && '0' <= p.data[p.pos] && p.data[p.pos] <= '9' {
+			p.pos++
+		}
+
+		if p.pos < len(p.data) && (p.data[p.pos] == 'e' || p.data[p.pos] == 'E') {
+			p.pos++
+			if p.pos < len(p.data) && (p.data[p.pos] == '-' || p.data[p.pos] == '+') {
+				p.pos++
+			}
+			for p.pos < len(p.data) && '0' <= p.data[p.pos] && p.data[p.pos] <= '9' {
+				p.pos++
+			}
+		}
+
+		f, err := strconv.ParseFloat(p.data[start:p.pos], 64)
+		if err != nil {
+			p.pos = start
+			return nil, p.errorf("invalid float %q", p.data[start:p.pos])
+		}
+		return &node{kind: nodeFloat, f: f}, nil
+	}
+
+	lit := strings.ToLower(p.data[digits:p.pos])
+	base := 10
+	switch {
+	case strings.HasPrefix(lit, "0x"):
+		base, lit = 16, lit[2:]
+	case strings.HasPrefix(lit, "0b"):
+		base, lit = 2, lit[2:]
+	case strings.HasPrefix(lit, "0o"):
+		base, lit = 8, lit[2:]
+	case len(lit) > 1 && lit[0] == '0' && strings.Trim(lit, "01234567") == "":
+		base = 8
+	}
+
+	u, err := strconv.ParseUint(lit, base, 64)
+	if err != nil || u > math.MaxInt64+1 || u == math.MaxInt64+1 && !neg {
+		return nil, &SyntaxError{msg: fmt.Sprintf("invalid number %q", p.data[start:p.pos]), Offset: start}
+	}
+
+	n := int64(u)
+	if neg {
+		n = -n
+	}
+	return &node{kind: nodeNumber, n: n}, nil
+}
+
+func isNumberChar(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// decode stores the parsed node in v
+func decode(n *node, v reflect.Value) error {
+	if ok, err := decodeMethods(n, v); ok {
+		return err
+	}
+
+	typeError := func() error {
+		return &UnmarshalTypeError{Value: n.kind.String(), Type: v.Type(), Offset: n.offset}
+	}
+
+	if n.kind == nodeNull {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decode(n, v.Elem())
+
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return typeError()
+		}
+		v.Set(reflect.ValueOf(n.value()))
+
+	case reflect.Bool:
+		switch n.kind {
+		case nodeBool:
+			v.SetBool(n.b)
+		case nodeNumber:
+			v.SetBool(n.n != 0)
+		default:
+			return typeError()
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n.kind != nodeNumber || v.OverflowInt(n.n) {
+			return typeError()
+		}
+		v.SetInt(n.n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n.kind != nodeNumber || n.n < 0 || v.OverflowUint(uint64(n.n)) {
+			return typeError()
+		}
+		v.SetUint(uint64(n.n))
+
+	case reflect.Float32, reflect.Float64:
+		switch n.kind {
+		case nodeFloat:
+			v.SetFloat(n.f)
+		case nodeNumber:
+			v.SetFloat(float64(n.n))
+		default:
+			return typeError()
+		}
+
+	case reflect.String:
+		if n.kind != nodeString {
+			return typeError()
+		}
+		v.SetString(n.s)
+
+	case reflect.Slice:
+		if n.kind != nodeList {
+			return typeError()
+		}
+
+		s := reflect.MakeSlice(v.Type(), len(n.list), len(n.list))
+		for i, item := range n.list {
+			if err := decode(item, s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+
+	case reflect.Array:
+		if n.kind != nodeList {
+			return typeError()
+		}
+
+		for i := 0; i < v.Len(); i++ {
+			if i < len(n.list) {
+				if err := decode(n.list[i], v.Index(i)); err != nil {
+					return err
+				}
+			} else {
+				v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+			}
+		}
+
+	case reflect.Map:
+		if n.kind != nodeDict {
+			return typeError()
+		}
+
+		kt := v.Type().Key()
+		if kt.Kind() != reflect.String && !reflect.PtrTo(kt).Implements(textUnmarshalerType) {
+			return fmt.Errorf("vim: unsupported map key type %s", kt)
+		}
+
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+
+		for i, key := range n.keys {
+			kv := reflect.New(kt).Elem()
+			if u, ok := kv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+				if err := u.UnmarshalText([]byte(key)); err != nil {
+					return err
+				}
+			} else {
+				kv.SetString(key)
+			}
+
+			ev := reflect.New(v.Type().Elem()).Elem()
+			if err := decode(n.items[i], ev); err != nil {
+				return err
+			}
+			v.SetMapIndex(kv, ev)
+		}
+
+	case reflect.Struct:
+		if n.kind != nodeDict {
+			return typeError()
+		}
+
+		fields := tags.Fields(v.Type(), "vim")
+		for i, key := range n.keys {
+			f := fieldByName(fields, key)
+			if f == nil {
+				continue
+			}
+
+			fv, err := fieldByIndex(v, f.Index)
+			if err != nil {
+				return err
+			}
+
+			item := n.items[i]
+			if f.Quoted && item.kind == nodeString {
+				// the value is encoded inside a string
+				p := &parser{data: item.s}
+				inner, err := p.parseValue()
+				if err != nil {
+					return err
+				}
+				item = inner
+			}
+
+			if err := decode(item, fv); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return typeError()
+	}
+
+	return nil
+}
+
+// decodeMethods decodes n with the UnmarshalVim or UnmarshalText method of
+// v, if it implements Unmarshaler or encoding.TextUnmarshaler. It returns
+// false if v doesn't implement any of them.
+func decodeMethods(n *node, v reflect.Value) (bool, error) {
+	if v.Kind() == reflect.Ptr || !v.CanAddr() {
+		return false, nil
+	}
+
+	pt := reflect.PtrTo(v.Type())
+	switch {
+	case pt.Implements(unmarshalerType):
+		return true, v.Addr().Interface().(Unmarshaler).UnmarshalVim([]byte(n.raw))
+	case pt.Implements(textUnmarshalerType):
+		if n.kind != nodeString {
+			return true, &UnmarshalTypeError{Value: n.kind.String(), Type: v.Type(), Offset: n.offset}
+		}
+		return true, v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(n.s))
+	}
+
+	return false, nil
+}
+
+// fieldByName returns the field with the given name, preferring an exact
+// match over a case insensitive one.
+func fieldByName(fields []tags.Field, name string) *tags.Field {
+	var fold *tags.Field
+	for i := range fields {
+		if fields[i].Name == name {
+			return &fields[i]
+		}
+		if fold == nil && strings.EqualFold(fields[i].Name, name) {
+			fold = &fields[i]
+		}
+	}
+	return fold
+}
+
+// fieldByIndex returns the nested field of v, allocating nil embedded struct
+// pointers.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for _, i := range index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("vim: cannot set embedded pointer to unexported struct: %v", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v, nil
+}
+
+// value returns the natural Go value of the node
+func (n *node) value() interface{} {
+	switch n.kind {
+	case nodeBool:
+		return n.b
+	case nodeNumber:
+		return n.n
+	case nodeFloat:
+		return n.f
+	case nodeString:
+		return n.s
+	case nodeList:
+		list := make([]interface{}, len(n.list))
+		for i, item := range n.list {
+			list[i] = item.value()
+		}
+		return list
+	case nodeDict:
+		dict := make(map[string]interface{}, len(n.keys))
+		for i, key := range n.keys {
+			dict[key] = n.items[i].value()
+		}
+		return dict
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+package vim
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type upper string
+
+func (u *upper) UnmarshalText(b []byte) error {
+	*u = upper(strings.ToUpper(string(b)))
+	return nil
+}
+
+type raw string
+
+func (r *raw) UnmarshalVim(b []byte) error {
+	*r = raw(b)
+	return nil
+}
+
+type Embedded struct {
+	E int `vim:"e"`
+}
+
+type decoded struct {
+	Embedded
+	Name    string            `vim:"name"`
+	Count   int               `vim:"count,omitempty"`
+	Ok      bool              `vim:"ok"`
+	Ratio   float64           `vim:"ratio"`
+	Quoted  int               `vim:"quoted,string"`
+	List    []string          `vim:"list"`
+	Dict    map[string]int    `vim:"dict"`
+	Ptr     *int              `vim:"ptr"`
+	Any     interface{}       `vim:"any"`
+	Text    upper             `vim:"text"`
+	Raw     raw               `vim:"raw"`
+	Ignored string            `vim:"-"`
+	Keys    map[upper]bool    `vim:"keys"`
+	Nested  map[string][]bool `vim:"nested"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	in := `{
+		'name': 'it''s', 'count': 0x1F, "ok": v:true, 'ratio': 1.5e-1,
+		'quoted': '42', 'list': ["a\tb", 'c',], 'dict': #{a-b: 1, c_d: -2},
+		'ptr': 0o17, 'any': {'x': [1, 2.0, v:null, 'y']}, 'text': 'abc',
+		'raw': [1, 2], '-': 'ignored', 'unknown': {'a': 1}, 'E': 3,
+		'keys': {'x': 1}, 'nested': {1: [v:false, 1, 0]},
+	}`
+
+	var got decoded
+	if err := Unmarshal([]byte(in), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	ptr := 15
+	want := decoded{
+		Embedded: Embedded{E: 3},
+		Name:     "it's",
+		Count:    31,
+		Ok:       true,
+		Ratio:    0.15,
+		Quoted:   42,
+		List:     []string{"a\tb", "c"},
+		Dict:     map[string]int{"a-b": 1, "c_d": -2},
+		Ptr:      &ptr,
+		Any: map[string]interface{}{
+			"x": []interface{}{int64(1), 2.0, nil, "y"},
+		},
+		Text:   "ABC",
+		Raw:    "[1, 2]",
+		Keys:   map[upper]bool{"X": true},
+		Nested: map[string][]bool{"1": {false, true, false}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal\nwant: %+v\ngot:  %+v", want, got)
+	}
+}
+
+func TestUnmarshal_Values(t *testing.T) {
+	cases := []struct {
+		in   string
+		want interface{}
+	}{
+		{`0`, int64(0)},
+		{`-12`, int64(-12)},
+		{`+7`, int64(7)},
+		{`017`, int64(15)},
+		{`0b101`, int64(5)},
+		{`0X10`, int64(16)},
+		{`089`, int64(89)},
+		{`-9223372036854775808`, int64(math.MinInt64)},
+		{`1.0`, 1.0},
+		{`-2.5E3`, -2500.0},
+		{`inf`, math.Inf(1)},
+		{`-inf`, math.Inf(-1)},
+		{`v:false`, false},
+		{`true`, true},
+		{`v:none`, nil},
+		{`null`, nil},
+		{`"\x41ä\U0001F389\101\e\z"`, "Aä🎉A\x1bz"},
+		{`''''`, "'"},
+		{`[]`, []interface{}{}},
+		{` [ [ ] , { } ] `, []interface{}{[]interface{}{}, map[string]interface{}{}}},
+		{`#{}`, map[string]interface{}{}},
+	}
+
+	for _, tc := range cases {
+		var got interface{}
+		if err := Unmarshal([]byte(tc.in), &got); err != nil {
+			t.Errorf("Unmarshal(%s): %s", tc.in, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Unmarshal(%s)\nwant: %#v\ngot:  %#v", tc.in, tc.want, got)
+		}
+	}
+
+	var f interface{}
+	if err := Unmarshal([]byte("nan"), &f); err != nil || !math.IsNaN(f.(float64)) {
+		t.Errorf("Unmarshal(nan) = %v, %v", f, err)
+	}
+}
+
+func TestUnmarshal_Errors(t *testing.T) {
+	cases := []struct {
+		in     string
+		v      interface{}
+		offset int
+		syntax bool
+	}{
+		{in: ``, v: new(interface{}), syntax: true},
+		{in: `{'a': 1`, v: new(interface{}), offset: 7, syntax: true},
+		{in: `{'a' 1}`, v: new(interface{}), offset: 5, syntax: true},
+		{in: `[1 2]`, v: new(interface{}), offset: 3, syntax: true},
+		{in: `'abc`, v: new(interface{}), offset: 4, syntax: true},
+		{in: `"\<Esc>"`, v: new(interface{}), offset: 1, syntax: true},
+		{in: `{[]: 1}`, v: new(interface{}), offset: 1, syntax: true},
+		{in: `0x`, v: new(interface{}), syntax: true},
+		{in: `9223372036854775808`, v: new(interface{}), syntax: true},
+		{in: `1 2`, v: new(interface{}), offset: 2, syntax: true},
+		{in: `foo`, v: new(interface{}), syntax: true},
+		{in: strings.Repeat("[", maxDepth+1), v: new(interface{}), offset: maxDepth, syntax: true},
+		{in: `[1, 'a']`, v: new([]int), offset: 4},
+		{in: `{'name': 1}`, v: new(decoded), offset: 9},
+		{in: `300`, v: new(int8)},
+		{in: `-1`, v: new(uint)},
+		{in: `1.5`, v: new(int)},
+	}
+
+	for _, tc := range cases {
+		err := Unmarshal([]byte(tc.in), tc.v)
+		if err == nil {
+			t.Errorf("Unmarshal(%s): expected an error", tc.in)
+			continue
+		}
+
+		var syntaxErr *SyntaxError
+		var typeErr *UnmarshalTypeError
+		switch {
+		case tc.syntax && errors.As(err, &syntaxErr):
+			if syntaxErr.Offset != tc.offset {
+				t.Errorf("Unmarshal(%s): %s\nwant offset: %d", tc.in, err, tc.offset)
+			}
+		case !tc.syntax && errors.As(err, &typeErr):
+			if typeErr.Offset != tc.offset {
+				t.Errorf("Unmarshal(%s): %s\nwant offset: %d", tc.in, err, tc.offset)
+			}
+		default:
+			t.Errorf("Unmarshal(%s): unexpected error %T: %s", tc.in, err, err)
+		}
+	}
+
+	var x int
+	if err := Unmarshal([]byte("1"), x); err == nil {
+		t.Error("Unmarshal into a non-pointer: expected an error")
+	}
+}
+
+func TestUnmarshal_RoundTrip(t *testing.T) {
+	type result struct {
+		Mode  string             `vim:"mode"`
+		Lines []int              `vim:"lines"`
+		Score float64            `vim:"score"`
+		Ok    bool               `vim:"ok"`
+		Meta  map[string]string  `vim:"meta,omitempty"`
+		Next  *result            `vim:"next,omitempty"`
+		Tags  map[string][]uint8 `vim:"tags"`
+	}
+
+	in := result{
+		Mode:  "enclosing\n'quoted'",
+		Lines: []int{1, -2, 3},
+		Score: 3,
+		Ok:    true,
+		Meta:  map[string]string{"a": "b", "üñí": "\x1b"},
+		Next:  &result{Mode: "next", Lines: []int{}, Tags: map[string][]uint8{}},
+		Tags:  map[string][]uint8{"x": {0, 255}},
+	}
+
+	out, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got result
+	if err := Unmarshal(out, &got); err != nil {
+		t.Fatalf("%s: %s", out, err)
+	}
+
+	if !reflect.DeepEqual(got, in) {
+		t.Errorf("round trip of %s\nwant: %+v\ngot:  %+v", out, in, got)
+	}
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	for _, s := range []string{
+		`{'a': [1, 2.5, "x\ny"], 'b': v:null}`,
+		`#{key-1: 0x1f, key_2: -0b10}`,
+		`['it''s', "ä\x41", v:true, v:false, 1.0e-3]`,
+		`017`, `inf`, `-nan`, `{1: 2}`,
+	} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, in string) {
+		var v interface{}
+		if err := Unmarshal([]byte(in), &v); err != nil {
+			return
+		}
+
+		out, err := Marshal(v)
+		if err != nil {
+			t.Fatalf("encoding the decoded %q failed: %s", in, err)
+		}
+
+		// floats are encoded the same as fmt formats them, which isn't
+		// always a Vimscript Float, i.e: NaN or 1e+06
+		if hasFloat(v) {
+			return
+		}
+
+		var again interface{}
+		if err := Unmarshal(out, &again); err != nil {
+			t.Fatalf("decoding the re-encoded %q failed: %s\n%s", in, err, out)
+		}
+
+		// NUL is encoded as a newline, so compare the encodings instead of
+		// the values
+		out2, err := Marshal(again)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(out) != string(out2) {
+			t.Fatalf("round trip of %q\nwant: %s\ngot:  %s", in, out, out2)
+		}
+	})
+}
+
+// hasFloat reports whether the decoded value contains a float
+func hasFloat(v interface{}) bool {
+	switch x := v.(type) {
+	case float64:
+		return true
+	case []interface{}:
+		for _, item := range x {
+			if hasFloat(item) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for _, item := range x {
+			if hasFloat(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	for _, s := range []string{"", "plain", "it's", "a\nb\t\"c\"", "\xff", "🎉​", `\<Esc>`} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, in string) {
+		if strings.ContainsRune(in, 0) {
+			t.Skip("NUL is encoded as a newline")
+		}
+
+		out, err := Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got string
+		if err := Unmarshal(out, &got); err != nil {
+			t.Fatalf("%s: %s", out, err)
+		}
+
+		if got != in {
+			t.Fatalf("round trip of %q\nencoded: %s\ngot: %q", in, out, got)
+		}
+	})
+}
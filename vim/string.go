@@ -0,0 +1,88 @@
+package vim
+
+import (
+	"bytes"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// writeString writes s as a Vimscript string literal. Strings which contain
+// only printable characters are written in single quotes, which don't
+// interpret backslashes. Otherwise the string is written in double quotes
+// with the escapes described in ":help expr-quote".
+//
+// Vim strings can't contain a NUL byte, it terminates the string. NUL is
+// written as a newline, the same way Vim represents NUL in buffer lines (see
+// ":help NL-used-for-Nul").
+func writeString(buf *bytes.Buffer, s string) {
+	if isPrintable(s) {
+		buf.WriteByte('\'')
+		for i := 0; i < len(s); i++ {
+			if s[i] == '\'' {
+				buf.WriteByte('\'')
+			}
+			buf.WriteByte(s[i])
+		}
+		buf.WriteByte('\'')
+		return
+	}
+
+	buf.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			// invalid UTF-8, keep the byte as is
+			fmt.Fprintf(buf, `\x%02x`, s[i])
+			i++
+			continue
+		}
+		i += size
+
+		switch r {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case '\n', 0:
+			buf.WriteString(`\n`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case 0x1b:
+			buf.WriteString(`\e`)
+		default:
+			switch {
+			case r < 0x80 && !unicode.IsPrint(r):
+				fmt.Fprintf(buf, `\x%02x`, r)
+			case !unicode.IsPrint(r) && r <= 0xffff:
+				fmt.Fprintf(buf, `\u%04x`, r)
+			case !unicode.IsPrint(r):
+				fmt.Fprintf(buf, `\U%08x`, r)
+			default:
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// isPrintable reports whether s is valid UTF-8 and contains only printable
+// characters, so it can be written as a single quoted string.
+func isPrintable(s string) bool {
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			return false
+		}
+		if !unicode.IsPrint(r) {
+			return false
+		}
+		i += size
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package vim
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteString(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"", `''`},
+		{"foo", `'foo'`},
+		{"it's", `'it''s'`},
+		{`back\slash`, `'back\slash'`},
+		{`"quoted"`, `'"quoted"'`},
+		{"üñíçødé 世界", `'üñíçødé 世界'`},
+		{"Name string `json:\"name\" vim:\"name\"`", "'Name string `json:\"name\" vim:\"name\"`'"},
+		{"a\nb", `"a\nb"`},
+		{"tab\there \"q\" \\", `"tab\there \"q\" \\"`},
+		{"\x01\x7f\x1b", `"\x01\x7f\e"`},
+		{"nul\x00", `"nul\n"`},
+		{"\xff invalid", `"\xff invalid"`},
+		{"zero\u200bwidth\n", `"zero\u200bwidth\n"`},
+		{"\U000e0001\n", `"\U000e0001\n"`},
+		{`\<Esc>` + "\n", `"\\<Esc>\n"`},
+	}
+
+	for _, tc := range cases {
+		buf := new(bytes.Buffer)
+		writeString(buf, tc.in)
+		if buf.String() != tc.want {
+			t.Errorf("writeString(%q)\nwant: %s\ngot:  %s", tc.in, tc.want, buf.String())
+		}
+	}
+}
+
+func TestWriteString_RoundTrip(t *testing.T) {
+	cases := []string{
+		"",
+		"plain",
+		"it's a 'quote'",
+		`C:\path\to\file`,
+		"unicode: äöü ß 日本語 🎉",
+		"control: \a\b\f\v\x01\x1f\x7f",
+		"newlines:\r\n\n",
+		"struct { Name string `json:\"name,omitempty\"` }",
+		"mixed \\ \" ' ` \t 🎉",
+		"\xc3\x28 invalid utf8",
+		"\u200b\u2028\ufeff",
+	}
+
+	for _, in := range cases {
+		buf := new(bytes.Buffer)
+		writeString(buf, in)
+
+		var out string
+		if err := Unmarshal(buf.Bytes(), &out); err != nil {
+			t.Fatalf("%q: %s", buf.String(), err)
+		}
+
+		if out != in {
+			t.Errorf("round trip of %q failed\nencoded: %s\ndecoded: %q", in, buf.String(), out)
+		}
+	}
+}
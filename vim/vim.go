@@ -7,11 +7,35 @@ package vim
 
 import (
 	"bytes"
+	"encoding"
 	"fmt"
 	"reflect"
+	"sort"
+
+	"github.com/fatih/motion/internal/tags"
+)
+
+// Marshaler is the interface implemented by types that can marshal
+// themselves into a valid Vimscript expression.
+type Marshaler interface {
+	MarshalVim() ([]byte, error)
+}
+
+var (
+	marshalerType     = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
 )
 
 // Marshal returns the Vimscript encoding of v.
+//
+// Struct fields are encoded according to the "vim" struct tag, which has the
+// same format and options as the "json" tag of the encoding/json package:
+// "-" omits the field, "omitempty" omits empty values and "string" encodes
+// strings, numbers and booleans inside a string. Embedded structs are
+// flattened following the same rules as encoding/json.
+//
+// Map keys are sorted, so the output is deterministic. Values implementing
+// Marshaler or encoding.TextMarshaler are encoded with their methods.
 func Marshal(x interface{}) ([]byte, error) {
 	var buf bytes.Buffer
 	if err := marshal(&buf, reflect.ValueOf(x)); err != nil {
@@ -21,6 +45,10 @@ func Marshal(x interface{}) ([]byte, error) {
 }
 
 func marshal(buf *bytes.Buffer, v reflect.Value) error {
+	if ok, err := marshalMethods(buf, v); ok {
+		return err
+	}
+
 	switch v.Kind() {
 	case reflect.Invalid:
 		buf.WriteString("null")
@@ -35,8 +63,7 @@ func marshal(buf *bytes.Buffer, v reflect.Value) error {
 		fmt.Fprint(buf, v.Interface())
 
 	case reflect.String:
-		// TODO(adonovan): check Go's escapes are supported by Vimscript.
-		fmt.Fprintf(buf, "%q", v.String())
+		writeString(buf, v.String())
 
 	case reflect.Ptr:
 		return marshal(buf, v.Elem())
@@ -55,58 +82,46 @@ func marshal(buf *bytes.Buffer, v reflect.Value) error {
 		buf.WriteByte(']')
 
 	case reflect.Map:
-		if v.Type().Key().Kind() != reflect.String {
-			return fmt.Errorf("non-string key type in %s", v.Type())
+		keys, err := mapKeys(v)
+		if err != nil {
+			return err
 		}
+
 		buf.WriteByte('{')
-		for i, k := range v.MapKeys() {
+		for i, k := range keys {
 			if i > 0 {
 				buf.WriteString(", ")
 			}
-			if err := marshal(buf, k); err != nil {
-				return err
-			}
+			writeString(buf, k.name)
 			buf.WriteString(": ")
-			if err := marshal(buf, v.MapIndex(k)); err != nil {
+			if err := marshal(buf, v.MapIndex(k.value)); err != nil {
 				return err
 			}
 		}
 		buf.WriteByte('}')
 
 	case reflect.Struct:
-		t := v.Type()
-		n := t.NumField()
 		buf.WriteByte('{')
 		sep := ""
-		for i := 0; i < n; i++ {
-			sf := t.Field(i)
-			if sf.PkgPath != "" { // unexported
-				continue
-			}
-
-			tag := sf.Tag.Get("vim")
-			if tag == "-" {
+		for _, f := range tags.Fields(v.Type(), "vim") {
+			fv, ok := f.Value(v)
+			if !ok {
 				continue
 			}
 
-			name, options := parseTag(tag)
-			if !isValidTag(name) {
-				name = ""
-			}
+			buf.WriteString(sep)
+			sep = ", "
 
-			if name == "" {
-				name = sf.Name
-			}
+			writeString(buf, f.Name)
+			buf.WriteString(": ")
 
-			fv := v.Field(i)
-			if !fv.IsValid() || options == "omitempty" && isEmptyValue(fv) {
+			if f.Quoted {
+				if err := marshalQuoted(buf, fv); err != nil {
+					return err
+				}
 				continue
 			}
 
-			buf.WriteString(sep)
-			sep = ", "
-
-			fmt.Fprintf(buf, "%q: ", name)
 			if err := marshal(buf, fv); err != nil {
 				return err
 			}
@@ -127,21 +142,84 @@ func marshal(buf *bytes.Buffer, v reflect.Value) error {
 	return nil
 }
 
-// from $GOROOT/src/encoding/json/encode.go
-func isEmptyValue(v reflect.Value) bool {
-	switch v.Kind() {
-	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
-		return v.Len() == 0
-	case reflect.Bool:
-		return !v.Bool()
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return v.Int() == 0
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		return v.Uint() == 0
-	case reflect.Float32, reflect.Float64:
-		return v.Float() == 0
-	case reflect.Interface, reflect.Ptr:
-		return v.IsNil()
+// marshalMethods encodes v with its MarshalVim or MarshalText method, if it
+// implements Marshaler or encoding.TextMarshaler. It returns false if v
+// doesn't implement any of them.
+func marshalMethods(buf *bytes.Buffer, v reflect.Value) (bool, error) {
+	if !v.IsValid() {
+		return false, nil
 	}
-	return false
+
+	// methods with pointer receivers of addressable values, i.e: struct
+	// fields
+	if v.Kind() != reflect.Ptr && v.CanAddr() &&
+		(reflect.PtrTo(v.Type()).Implements(marshalerType) ||
+			reflect.PtrTo(v.Type()).Implements(textMarshalerType)) {
+		v = v.Addr()
+	}
+
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return false, nil
+	}
+
+	switch {
+	case v.Type().Implements(marshalerType):
+		b, err := v.Interface().(Marshaler).MarshalVim()
+		if err != nil {
+			return true, fmt.Errorf("error calling MarshalVim for type %s: %s", v.Type(), err)
+		}
+		buf.Write(b)
+		return true, nil
+	case v.Type().Implements(textMarshalerType):
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return true, fmt.Errorf("error calling MarshalText for type %s: %s", v.Type(), err)
+		}
+		writeString(buf, string(b))
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// marshalQuoted encodes v inside a string, for fields with the "string" tag
+// option.
+func marshalQuoted(buf *bytes.Buffer, v reflect.Value) error {
+	var quoted bytes.Buffer
+	if err := marshal(&quoted, v); err != nil {
+		return err
+	}
+	writeString(buf, quoted.String())
+	return nil
+}
+
+type mapKey struct {
+	name  string
+	value reflect.Value
+}
+
+// mapKeys returns the keys of the given map sorted by their string
+// representation. Keys must be strings or implement encoding.TextMarshaler.
+func mapKeys(v reflect.Value) ([]mapKey, error) {
+	kt := v.Type().Key()
+	isText := kt.Kind() != reflect.String && kt.Implements(textMarshalerType)
+	if kt.Kind() != reflect.String && !isText {
+		return nil, fmt.Errorf("non-string key type in %s", v.Type())
+	}
+
+	keys := make([]mapKey, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		name := k.String()
+		if isText && !(k.Kind() == reflect.Ptr && k.IsNil()) {
+			b, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return nil, fmt.Errorf("error calling MarshalText for type %s: %s", kt, err)
+			}
+			name = string(b)
+		}
+		keys = append(keys, mapKey{name: name, value: k})
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].name < keys[j].name })
+	return keys, nil
 }
@@ -0,0 +1,215 @@
+package vim
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+type text string
+
+func (t text) MarshalText() ([]byte, error) { return []byte(strings.ToUpper(string(t))), nil }
+
+type id int
+
+func (i id) MarshalText() ([]byte, error) { return []byte(fmt.Sprintf("id-%d", i)), nil }
+
+type custom struct{ err error }
+
+func (c *custom) MarshalVim() ([]byte, error) { return []byte("v:true"), c.err }
+
+type Inner struct {
+	A int `vim:"a"`
+	B int `vim:"b,omitempty"`
+}
+
+type Other struct {
+	A int    `vim:"a"`
+	C string `vim:"c"`
+}
+
+func TestMarshal(t *testing.T) {
+	cases := []struct {
+		name string
+		in   interface{}
+		want string
+	}{
+		{
+			name: "sorted map",
+			in:   map[string]int{"c": 3, "a": 1, "b": 2},
+			want: `{'a': 1, 'b': 2, 'c': 3}`,
+		},
+		{
+			name: "text marshaler map keys",
+			in:   map[id]int{2: 2, 1: 1},
+			want: `{'id-1': 1, 'id-2': 2}`,
+		},
+		{
+			name: "omitempty with other options",
+			in: struct {
+				A int    `vim:"a,string,omitempty"`
+				B string `vim:",omitempty"`
+				C []int  `vim:"c,omitempty"`
+			}{},
+			want: `{}`,
+		},
+		{
+			name: "string option",
+			in: struct {
+				A int     `vim:"a,string"`
+				B bool    `vim:"b,string"`
+				C string  `vim:"c,string"`
+				D float64 `vim:"d,string"`
+				E []int   `vim:"e,string"`
+			}{A: 1, B: true, C: "x", D: 1.5, E: []int{1}},
+			want: `{'a': '1', 'b': 'true', 'c': '''x''', 'd': '1.5', 'e': [1]}`,
+		},
+		{
+			name: "dash",
+			in: struct {
+				A int `vim:"-"`
+				B int `vim:"-,"`
+				C int
+			}{A: 1, B: 2, C: 3},
+			want: `{'-': 2, 'C': 3}`,
+		},
+		{
+			name: "embedded",
+			in: struct {
+				Inner
+				D int `vim:"d"`
+			}{Inner: Inner{A: 1}, D: 2},
+			want: `{'a': 1, 'd': 2}`,
+		},
+		{
+			name: "embedded pointer",
+			in: struct {
+				*Inner
+				D int `vim:"d"`
+			}{D: 2},
+			want: `{'d': 2}`,
+		},
+		{
+			name: "embedded with tag",
+			in: struct {
+				Inner `vim:"inner"`
+			}{Inner: Inner{A: 1}},
+			want: `{'inner': {'a': 1}}`,
+		},
+		{
+			name: "embedded conflict",
+			in: struct {
+				Inner
+				Other
+			}{Inner: Inner{A: 1}, Other: Other{A: 2, C: "c"}},
+			want: `{'c': 'c'}`,
+		},
+		{
+			name: "embedded shadowed",
+			in: struct {
+				Inner
+				A string `vim:"a"`
+			}{Inner: Inner{A: 1}, A: "outer"},
+			want: `{'a': 'outer'}`,
+		},
+		{
+			name: "text marshaler",
+			in: struct {
+				T text `vim:"t"`
+			}{T: "foo"},
+			want: `{'t': 'FOO'}`,
+		},
+		{
+			name: "marshaler",
+			in: &struct {
+				C  custom  `vim:"c"`
+				CP *custom `vim:"cp,omitempty"`
+			}{},
+			want: `{'c': v:true}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := Marshal(tc.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(out) != tc.want {
+				t.Errorf("wrong output:\nwant: %s\ngot:  %s", tc.want, out)
+			}
+		})
+	}
+}
+
+func TestMarshal_Errors(t *testing.T) {
+	if _, err := Marshal(map[int]int{1: 1}); err == nil {
+		t.Error("non-string map keys should fail")
+	}
+
+	if _, err := Marshal(&custom{err: errors.New("failed")}); err == nil || !strings.Contains(err.Error(), "failed") {
+		t.Errorf("MarshalVim error should be returned, got: %v", err)
+	}
+}
+
+// TestMarshal_Golden checks that the output of a result is deterministic. Run
+// the tests with -update to update the golden file.
+func TestMarshal_Golden(t *testing.T) {
+	type position struct {
+		Filename string `vim:"filename"`
+		Line     int    `vim:"line"`
+		Col      int    `vim:"col"`
+	}
+
+	type result struct {
+		Mode   string               `vim:"mode"`
+		Tags   map[string]string    `vim:"tags"`
+		Decls  []map[string]string  `vim:"decls,omitempty"`
+		Pos    *position            `vim:"pos,omitempty"`
+		Nested map[string]*position `vim:"nested"`
+	}
+
+	in := &result{
+		Mode: "decls",
+		Tags: map[string]string{"json": "name,omitempty", "vim": "name", "xml": "-"},
+		Decls: []map[string]string{
+			{"keyword": "func", "ident": "Foo", "full": "func Foo(s string) error"},
+			{"keyword": "type", "ident": "T", "full": "type T struct{Name string \"json:\\\"name\\\"\"}"},
+		},
+		Pos: &position{Filename: "main.go", Line: 1, Col: 1},
+		Nested: map[string]*position{
+			"z": {Filename: "z.go", Line: 3, Col: 1},
+			"a": {Filename: "a.go", Line: 1, Col: 2},
+			"m": {Filename: "ü.go", Line: 2, Col: 3},
+		},
+	}
+
+	out, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = append(out, '\n')
+
+	golden := filepath.Join("testdata", "result.golden")
+	if *update {
+		if err := os.WriteFile(golden, out, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(out) != string(want) {
+		t.Errorf("output doesn't match %s:\nwant: %s\ngot:  %s", golden, want, out)
+	}
+}